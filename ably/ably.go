@@ -0,0 +1,144 @@
+package ably
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+const (
+	PROTOCOL_VERSION = "2"
+	// extra time to wait for a heartbeat after the server's max idle interval.
+	HEARTBEAT_GRACE = time.Second * 10
+	RECONNECT_DELAY = time.Second * 15
+)
+
+var (
+	ErrDisconnected = errors.New("ably: connection closed before ack")
+)
+
+// Ack for a published message or presence update.
+type Ack struct {
+	msg     *ProtocolMessage
+	proto   *ablyProtocol
+	sent    bool
+	done    chan error
+}
+
+// Wait for the server to ACK/NACK the message.
+func (a *Ack) Wait(ctx context.Context) error {
+	select {
+	case err := <-a.done:
+		return err
+	case <-ctx.Done():
+		a.proto.forget(a)
+		return ctx.Err()
+	}
+}
+
+func (a *Ack) finish(err error) {
+	select {
+	case a.done <- err:
+	default:
+	}
+}
+
+type AblyClient struct {
+	*ws.ProtocolClient
+	cf      AblyConfig
+	proto   *ablyProtocol
+}
+
+// Current connection id, empty if not connected.
+func (c *AblyClient) ConnectionId() string {
+	c.proto.Lock()
+	defer c.proto.Unlock()
+	return c.proto.connectionId
+}
+
+func (c *AblyClient) Close() {
+	c.proto.send(&ProtocolMessage{Action: actionClose})
+	c.ProtocolClient.Close()
+	c.proto.failPending()
+}
+
+func (c *AblyClient) Channel(name string) *Channel {
+	if ch := c.proto.channel(name); ch != nil {
+		return ch
+	}
+	// create a new channel, it is attached once connected.
+	ch := NewChannel(name, c)
+	c.AddChannel(name, ch)
+	return ch
+}
+
+func (c *AblyClient) Subscribe(channel string) ws.Channel {
+	return c.Channel(channel)
+}
+
+type AblyConfig struct {
+	ws.Config
+	// API key ("name:secret"), used if there is no token.
+	Key             string
+	Token           string
+	// Called to get a new token when the current one expires.
+	AuthCallback    func() (string, error)
+	ClientId        string
+}
+
+var (
+	DefaultAbly = AblyConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 15,
+			ActivityTimeout: time.Second * 25,
+			PingTimeout:     time.Second * 10,
+		},
+	}
+	// Fallback hosts of the production endpoint.
+	AblyFallbackUrls = []string{
+		"wss://a.ably-realtime.com",
+		"wss://b.ably-realtime.com",
+		"wss://c.ably-realtime.com",
+		"wss://d.ably-realtime.com",
+		"wss://e.ably-realtime.com",
+	}
+)
+
+func (cf AblyConfig) NewAblyUrl(realtimeUrl string) (*AblyClient, error) {
+	u, err := url.Parse(realtimeUrl)
+	if err != nil {
+		return nil, err
+	}
+	proto := &ablyProtocol{
+		cf: cf,
+		token: cf.Token,
+		stateTtl: time.Minute * 2,
+	}
+	return &AblyClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		cf: cf,
+		proto: proto,
+	}, nil
+}
+
+// Connect to the production endpoint, with its fallback hosts unless
+// FallbackUrls is set.
+func (cf AblyConfig) NewAbly() (*AblyClient, error) {
+	if len(cf.FallbackUrls) == 0 {
+		cf.FallbackUrls = AblyFallbackUrls
+	}
+	return cf.NewAblyUrl("wss://realtime.ably.io")
+}
+
+func NewAblyUrl(realtimeUrl string) (*AblyClient, error) {
+	return DefaultAbly.NewAblyUrl(realtimeUrl)
+}
+
+func NewAbly(key string) (*AblyClient, error) {
+	cf := DefaultAbly
+	cf.Key = key
+	return cf.NewAbly()
+}
@@ -0,0 +1,221 @@
+package ably
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn, r *http.Request)) string {
+	return wstest.Serve(t, wstest.Handler(t, handler))
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) *ProtocolMessage {
+	msg := &ProtocolMessage{}
+	if err := conn.ReadJSON(msg); err != nil {
+		t.Error(err)
+		return nil
+	}
+	return msg
+}
+
+func TestDataEncoding(t *testing.T) {
+	data, enc := encodeData(map[string]interface{}{"a": 1.0})
+	if enc != "json" || !reflect.DeepEqual(decodeData(data, enc), map[string]interface{}{"a": 1.0}) {
+		t.Fatalf("bad json round trip: %v %s", data, enc)
+	}
+	data, enc = encodeData([]byte("hi"))
+	if enc != "base64" || !reflect.DeepEqual(decodeData(data, enc), []byte("hi")) {
+		t.Fatalf("bad base64 round trip: %v %s", data, enc)
+	}
+	if s := decodeData("aGk=", "utf-8/base64"); s != "hi" {
+		t.Fatalf("expected hi, got %v", s)
+	}
+	// unsupported encodings are left alone.
+	if s := decodeData("xyz", "cipher+aes"); s != "xyz" {
+		t.Fatalf("expected xyz, got %v", s)
+	}
+}
+
+func TestConnectUrl(t *testing.T) {
+	p := &ablyProtocol{
+		cf: AblyConfig{Key: "app.key:secret", ClientId: "bob"},
+		stateTtl: time.Minute,
+	}
+	u, _ := url.Parse(p.ConnectUrl("wss://realtime.ably.io"))
+	q := u.Query()
+	if q.Get("key") != "app.key:secret" || q.Get("clientId") != "bob" || q.Get("format") != "json" || q.Get("resume") != "" {
+		t.Fatalf("bad connect url: %s", u)
+	}
+	// resume a recent connection.
+	p.connectionKey = "ck1"
+	p.connected = true
+	p.Closed()
+	u, _ = url.Parse(p.ConnectUrl("wss://realtime.ably.io"))
+	if u.Query().Get("resume") != "ck1" {
+		t.Fatalf("expected resume, got %s", u)
+	}
+	// a token is used instead of the key.
+	p.cf.AuthCallback = func() (string, error) {
+		return "tok", nil
+	}
+	u, _ = url.Parse(p.ConnectUrl("wss://realtime.ably.io"))
+	if u.Query().Get("access_token") != "tok" || u.Query().Get("key") != "" {
+		t.Fatalf("expected token, got %s", u)
+	}
+}
+
+func TestAttachAndPublish(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn, r *http.Request) {
+		if r.URL.Query().Get("key") != "app.key:secret" {
+			t.Errorf("missing key: %s", r.URL)
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`not json`))
+		conn.WriteJSON(&ProtocolMessage{
+			Action: actionConnected,
+			ConnectionId: "c1",
+			ConnectionDetails: &ConnectionDetails{ConnectionKey: "ck1", MaxIdleInterval: 15000},
+		})
+		attach := readMessage(t, conn)
+		if attach == nil || attach.Action != actionAttach || attach.Channel != "test" {
+			t.Errorf("expected ATTACH, got %+v", attach)
+			return
+		}
+		conn.WriteJSON(&ProtocolMessage{Action: actionAttached, Channel: "test", ChannelSerial: "s1"})
+		conn.WriteJSON(&ProtocolMessage{
+			Action: actionMessage,
+			Channel: "test",
+			Id: "m1",
+			ChannelSerial: "s2",
+			Messages: []*Message{{Name: "greet", Data: `{"a":1}`, Encoding: "json"}},
+		})
+		pub := readMessage(t, conn)
+		if pub == nil || pub.Action != actionMessage || len(pub.Messages) != 1 || pub.Messages[0].Name != "reply" {
+			t.Errorf("expected MESSAGE, got %+v", pub)
+			return
+		}
+		conn.WriteJSON(&ProtocolMessage{Action: actionAck, MsgSerial: pub.MsgSerial, Count: 1})
+		<-done
+	})
+	defer close(done)
+	cf := DefaultAbly
+	cf.Key = "app.key:secret"
+	client, err := cf.NewAblyUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	// a custom url doesn't fall back to the Ably hosts.
+	if len(client.cf.FallbackUrls) != 0 {
+		t.Fatalf("unexpected fallback urls: %v", client.cf.FallbackUrls)
+	}
+	got := make(chan ws.Event, 1)
+	ch := client.Channel("test")
+	ch.BindFunc("greet", func(e ws.Event) {
+		got <- e
+	})
+	select {
+	case e := <-got:
+		ev := e.(*Event)
+		if ev.Id != "m1:0" || !reflect.DeepEqual(ev.Data, map[string]interface{}{"a": 1.0}) {
+			t.Fatalf("bad event: %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	if client.ConnectionId() != "c1" {
+		t.Fatalf("expected connection c1, got %s", client.ConnectionId())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	if err := ch.Publish("reply", "yo").Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ch.Serial() != "s2" {
+		t.Fatalf("expected serial s2, got %s", ch.Serial())
+	}
+}
+
+func TestResumeAttach(t *testing.T) {
+	done := make(chan struct{})
+	var conns int32
+	u := testServer(t, func(conn *websocket.Conn, r *http.Request) {
+		n := atomic.AddInt32(&conns, 1)
+		conn.WriteJSON(&ProtocolMessage{
+			Action: actionConnected,
+			ConnectionId: "c" + strconv.Itoa(int(n)),
+			ConnectionDetails: &ConnectionDetails{ConnectionKey: "ck", MaxIdleInterval: 15000},
+		})
+		attach := readMessage(t, conn)
+		if attach == nil || attach.Action != actionAttach {
+			t.Errorf("expected ATTACH, got %+v", attach)
+			return
+		}
+		switch n {
+		case 1:
+			if attach.ChannelSerial != "" {
+				t.Errorf("unexpected serial: %+v", attach)
+			}
+			conn.WriteJSON(&ProtocolMessage{Action: actionAttached, Channel: "test", ChannelSerial: "s1"})
+			conn.WriteJSON(&ProtocolMessage{
+				Action: actionMessage,
+				Channel: "test",
+				ChannelSerial: "s2",
+				Messages: []*Message{{Name: "greet", Data: "a"}},
+			})
+			return
+		case 2:
+			if attach.ChannelSerial != "s2" {
+				t.Errorf("expected serial s2, got %+v", attach)
+			}
+			// continuity kept, no gap.
+			conn.WriteJSON(&ProtocolMessage{Action: actionAttached, Channel: "test", ChannelSerial: "s2", Flags: flagResumed})
+			conn.WriteJSON(&ProtocolMessage{
+				Action: actionMessage,
+				Channel: "test",
+				ChannelSerial: "s3",
+				Messages: []*Message{{Name: "greet", Data: "b"}},
+			})
+			return
+		}
+		if attach.ChannelSerial != "s3" {
+			t.Errorf("expected serial s3, got %+v", attach)
+		}
+		conn.WriteJSON(&ProtocolMessage{Action: actionAttached, Channel: "test", ChannelSerial: "s9"})
+		<-done
+	})
+	defer close(done)
+	client, err := NewAblyUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 3)
+	ch := client.Channel("test")
+	ch.BindFunc("greet", func(e ws.Event) {
+		got <- e
+	})
+	ch.BindFunc(ws.GAP_EVENT, func(e ws.Event) {
+		got <- e
+	})
+	for _, expected := range []string{"greet:a", "greet:b", ws.GAP_EVENT + ":s3"} {
+		select {
+		case e := <-got:
+			if e.GetEvent() + ":" + e.GetDataString() != expected {
+				t.Fatalf("expected %s, got %s:%s", expected, e.GetEvent(), e.GetDataString())
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for", expected)
+		}
+	}
+}
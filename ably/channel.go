@@ -0,0 +1,118 @@
+package ably
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"log"
+	"sync"
+)
+
+type Channel struct {
+	*ws.PublicChannel
+	proto    *ablyProtocol
+	Name     string
+	mu       sync.Mutex
+	attached bool
+}
+
+// Serial of the last message seen, used to resume the channel on attach.
+func (c *Channel) Serial() string {
+	return c.proto.client.LastMessageId(c.Name)
+}
+
+func (c *Channel) setAttached(attached bool) {
+	c.mu.Lock()
+	c.attached = attached
+	c.mu.Unlock()
+	c.SetActive(attached)
+}
+
+func (c *Channel) handleProtocol(msg *ProtocolMessage) {
+	switch msg.Action {
+	case actionAttached:
+		c.mu.Lock()
+		c.attached = true
+		c.mu.Unlock()
+		if msg.Flags & flagResumed == 0 {
+			// server couldn't continue from our channel serial.
+			c.proto.client.ResumeFailed(c.Name)
+		}
+		c.proto.client.Subscribed(c.Name)
+		c.proto.client.Seen(c.Name, msg.ChannelSerial)
+	case actionDetached:
+		c.mu.Lock()
+		unexpected := c.attached
+		c.mu.Unlock()
+		c.setAttached(false)
+		if unexpected {
+			// server detached us, attach again.
+			log.Println("Ably channel detached:", c.Name, msg.Error)
+			c.Subscribe()
+		}
+	case actionError:
+		log.Println("Ably channel error:", c.Name, msg.Error)
+		c.setAttached(false)
+		c.proto.client.Dispatch(&Event{
+			Channel: c.Name,
+			Name: "error",
+			Data: msg.Error,
+		})
+	}
+}
+
+func (c *Channel) UpdateClientState(connected bool) {
+	if connected {
+		// Client connected, (re)attach from the last channel serial.
+		c.Subscribe()
+	} else {
+		c.SetActive(false)
+	}
+}
+
+func (c *Channel) Unsubscribe() {
+	c.setAttached(false)
+	c.PublicChannel.Unsubscribe()
+}
+
+// Publish a message to the channel.
+func (c *Channel) Publish(name string, data interface{}) *Ack {
+	data, encoding := encodeData(data)
+	return c.proto.publish(&ProtocolMessage{
+		Action: actionMessage,
+		Channel: c.Name,
+		Messages: []*Message{{Name: name, Data: data, Encoding: encoding}},
+	})
+}
+
+func (c *Channel) presence(action int, data interface{}) *Ack {
+	data, encoding := encodeData(data)
+	return c.proto.publish(&ProtocolMessage{
+		Action: actionPresence,
+		Channel: c.Name,
+		Presence: []*PresenceMessage{{Action: action, Data: data, Encoding: encoding}},
+	})
+}
+
+func (c *Channel) EnterPresence(data interface{}) *Ack {
+	return c.presence(presenceEnter, data)
+}
+
+func (c *Channel) UpdatePresence(data interface{}) *Ack {
+	return c.presence(presenceUpdate, data)
+}
+
+func (c *Channel) LeavePresence(data interface{}) *Ack {
+	return c.presence(presenceLeave, data)
+}
+
+func newChannel(name string, proto *ablyProtocol) *Channel {
+	return &Channel{
+		PublicChannel: ws.NewPublicChannel(name, proto.client),
+		proto: proto,
+		Name: name,
+	}
+}
+
+func NewChannel(name string, client *AblyClient) *Channel {
+	return newChannel(name, client.proto)
+}
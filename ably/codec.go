@@ -0,0 +1,434 @@
+package ably
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Codec for Ably protocol messages, also tracks the connection for resumes
+// and the messages waiting for an ACK.
+type ablyProtocol struct {
+	sync.Mutex
+	client          *ws.ProtocolClient
+	cf              AblyConfig
+	token           string
+	connected       bool
+	connectionId    string
+	connectionKey   string
+	stateTtl        time.Duration
+	disconnectedAt  time.Time
+	msgSerial       int64
+	pending         []*Ack
+}
+
+func (p *ablyProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *ablyProtocol) isConnected() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.connected
+}
+
+func (p *ablyProtocol) marshal(msg *ProtocolMessage) []byte {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("Error encoding message:", err)
+		return nil
+	}
+	return buf
+}
+
+func (p *ablyProtocol) send(msg *ProtocolMessage) {
+	// attach/detach are sent again when (re)connected.
+	if !p.isConnected() {
+		return
+	}
+	p.sendMessage(msg)
+}
+
+func (p *ablyProtocol) sendMessage(msg *ProtocolMessage) {
+	if buf := p.marshal(msg); buf != nil {
+		p.client.SendMessage(buf)
+	}
+}
+
+// Queue a message that needs an ACK, it is sent again after a reconnect.
+func (p *ablyProtocol) publish(msg *ProtocolMessage) *Ack {
+	ack := &Ack{
+		msg: msg,
+		proto: p,
+		done: make(chan error, 1),
+	}
+	p.Lock()
+	p.pending = append(p.pending, ack)
+	connected := p.connected
+	if connected {
+		msg.MsgSerial = p.msgSerial
+		p.msgSerial++
+		ack.sent = true
+	}
+	p.Unlock()
+	if connected {
+		p.sendMessage(msg)
+	}
+	return ack
+}
+
+func (p *ablyProtocol) forget(ack *Ack) {
+	p.Lock()
+	defer p.Unlock()
+	for i, a := range p.pending {
+		if a == ack {
+			p.pending = append(p.pending[:i], p.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// Fail all messages waiting for an ACK.
+func (p *ablyProtocol) failPending() {
+	p.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.Unlock()
+	for _, ack := range pending {
+		ack.finish(ErrDisconnected)
+	}
+}
+
+func (p *ablyProtocol) getToken() (string, error) {
+	p.Lock()
+	token := p.token
+	p.Unlock()
+	if token != "" || p.cf.AuthCallback == nil {
+		return token, nil
+	}
+	token, err := p.cf.AuthCallback()
+	if err != nil {
+		return "", err
+	}
+	p.Lock()
+	p.token = token
+	p.Unlock()
+	return token, nil
+}
+
+// Add auth, format and resume params to the url for each connect attempt.
+func (p *ablyProtocol) ConnectUrl(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	q := pu.Query()
+	q.Set("v", PROTOCOL_VERSION)
+	q.Set("format", "json")
+	q.Set("heartbeats", "true")
+	token, err := p.getToken()
+	if err != nil {
+		log.Println("Ably auth callback failed:", err)
+	}
+	if token != "" {
+		q.Set("access_token", token)
+	} else if p.cf.Key != "" {
+		q.Set("key", p.cf.Key)
+	}
+	if p.cf.ClientId != "" {
+		q.Set("clientId", p.cf.ClientId)
+	}
+	p.Lock()
+	if p.connectionKey != "" && time.Since(p.disconnectedAt) < p.stateTtl {
+		q.Set("resume", p.connectionKey)
+	}
+	p.Unlock()
+	pu.RawQuery = q.Encode()
+	return pu.String()
+}
+
+func (p *ablyProtocol) Opened() bool {
+	// wait for the CONNECTED message.
+	return false
+}
+
+func (p *ablyProtocol) Closed() {
+	p.Lock()
+	defer p.Unlock()
+	if p.connected {
+		p.disconnectedAt = time.Now()
+	}
+	p.connected = false
+}
+
+func (p *ablyProtocol) HandshakeFrame() []byte {
+	return nil
+}
+
+// Events are published on their channel, they are sent once connected.
+func (p *ablyProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	if ch := p.channel(e.GetChannel()); ch != nil {
+		ch.Publish(e.GetEvent(), e.GetData())
+	}
+	// queued by publish.
+	return nil, nil
+}
+
+func (p *ablyProtocol) DecodeEvent(buf []byte) (ws.Event, error) {
+	events, err := p.DecodeEvents(buf)
+	if len(events) == 0 {
+		return nil, err
+	}
+	return events[0], err
+}
+
+func (p *ablyProtocol) DecodeEvents(buf []byte) ([]ws.Event, error) {
+	msg := &ProtocolMessage{}
+	if err := json.Unmarshal(buf, msg); err != nil {
+		return nil, err
+	}
+	switch msg.Action {
+	case actionMessage, actionPresence, actionSync:
+		return p.channelEvents(msg), nil
+	}
+	// handled by Classify.
+	return []ws.Event{&Event{msg: msg}}, nil
+}
+
+func (p *ablyProtocol) channelEvents(msg *ProtocolMessage) []ws.Event {
+	events := make([]ws.Event, 0, len(msg.Messages) + len(msg.Presence))
+	for i, m := range msg.Messages {
+		if m.Id == "" && msg.Id != "" {
+			m.Id = fmt.Sprintf("%s:%d", msg.Id, i)
+		}
+		if m.Timestamp == 0 {
+			m.Timestamp = msg.Timestamp
+		}
+		e := messageEvent(msg.Channel, m)
+		e.serial = msg.ChannelSerial
+		events = append(events, e)
+	}
+	for i, m := range msg.Presence {
+		if m.Id == "" && msg.Id != "" {
+			m.Id = fmt.Sprintf("%s:%d", msg.Id, i)
+		}
+		if m.Timestamp == 0 {
+			m.Timestamp = msg.Timestamp
+		}
+		e := presenceEvent(msg.Channel, m)
+		e.serial = msg.ChannelSerial
+		events = append(events, e)
+	}
+	return events
+}
+
+func (p *ablyProtocol) handleConnected(msg *ProtocolMessage) {
+	details := msg.ConnectionDetails
+	if details == nil {
+		details = &ConnectionDetails{}
+	}
+	p.Lock()
+	resumed := msg.Flags & flagResumed != 0 || (p.connectionId != "" && p.connectionId == msg.ConnectionId)
+	p.connected = true
+	p.connectionId = msg.ConnectionId
+	p.connectionKey = details.ConnectionKey
+	if details.ConnectionStateTtl > 0 {
+		p.stateTtl = time.Duration(details.ConnectionStateTtl) * time.Millisecond
+	}
+	if !resumed {
+		// new connection, messages are renumbered.
+		p.msgSerial = 0
+	}
+	// send messages that were not acked yet.
+	pending := make([]*Ack, len(p.pending))
+	copy(pending, p.pending)
+	for _, ack := range pending {
+		// a resumed connection keeps the serials that were already sent.
+		if !resumed || !ack.sent {
+			ack.msg.MsgSerial = p.msgSerial
+			p.msgSerial++
+			ack.sent = true
+		}
+	}
+	p.Unlock()
+	if !resumed && msg.Error != nil {
+		log.Println("Ably resume failed:", msg.Error)
+	}
+	if details.MaxIdleInterval > 0 {
+		p.client.Socket().SetActivityTimeout(time.Duration(details.MaxIdleInterval) * time.Millisecond + HEARTBEAT_GRACE)
+	}
+	for _, ack := range pending {
+		p.sendMessage(ack.msg)
+	}
+	p.client.Connected()
+}
+
+func (p *ablyProtocol) handleAck(msg *ProtocolMessage) {
+	var err error
+	if msg.Action == actionNack {
+		err = msg.Error
+		if msg.Error == nil {
+			err = &ErrorInfo{Code: 50000, StatusCode: 500, Message: "message rejected"}
+		}
+	}
+	count := int64(msg.Count)
+	if count == 0 {
+		count = 1
+	}
+	p.Lock()
+	var done []*Ack
+	pending := p.pending[:0]
+	for _, ack := range p.pending {
+		serial := ack.msg.MsgSerial
+		if serial >= msg.MsgSerial && serial < msg.MsgSerial + count {
+			done = append(done, ack)
+		} else {
+			pending = append(pending, ack)
+		}
+	}
+	p.pending = pending
+	p.Unlock()
+	for _, ack := range done {
+		ack.finish(err)
+	}
+}
+
+// Connection level error, refresh the token if it expired.
+func (p *ablyProtocol) handleError(info *ErrorInfo) error {
+	if info == nil {
+		return ws.ErrReconnect
+	}
+	log.Println("Ably error:", info)
+	if info.tokenError() {
+		if p.cf.AuthCallback == nil {
+			return ws.NewError(info.Error(), false, false, 0)
+		}
+		p.Lock()
+		p.token = ""
+		p.Unlock()
+		return ws.ErrReconnect
+	}
+	if info.StatusCode >= 500 {
+		return ws.NewError(info.Error(), false, true, RECONNECT_DELAY)
+	}
+	return ws.NewError(info.Error(), false, false, 0)
+}
+
+// Server asked us to re-authenticate on the current connection.
+func (p *ablyProtocol) handleAuth() {
+	p.Lock()
+	p.token = ""
+	p.Unlock()
+	go func() {
+		token, err := p.getToken()
+		if err != nil || token == "" {
+			log.Println("Ably re-auth failed:", err)
+			return
+		}
+		msg := &ProtocolMessage{Action: actionAuth}
+		msg.Auth = &struct {
+			AccessToken string `json:"accessToken"`
+		}{AccessToken: token}
+		p.send(msg)
+	}()
+}
+
+func (p *ablyProtocol) channel(name string) *Channel {
+	ch, _ := p.client.FindChannel(name).(*Channel)
+	return ch
+}
+
+func (p *ablyProtocol) handleMessage(msg *ProtocolMessage) error {
+	switch msg.Action {
+	case actionHeartbeat:
+		p.client.Socket().HandlePong()
+	case actionConnected:
+		p.handleConnected(msg)
+	case actionDisconnected:
+		if msg.Error != nil && msg.Error.tokenError() {
+			return p.handleError(msg.Error)
+		}
+		log.Println("Ably disconnected:", msg.Error)
+		return ws.ErrReconnect
+	case actionClosed:
+		return ws.ErrClosed
+	case actionError:
+		if msg.Channel == "" {
+			return p.handleError(msg.Error)
+		}
+		if ch := p.channel(msg.Channel); ch != nil {
+			ch.handleProtocol(msg)
+		}
+	case actionAck, actionNack:
+		p.handleAck(msg)
+	case actionAttached, actionDetached:
+		if ch := p.channel(msg.Channel); ch != nil {
+			ch.handleProtocol(msg)
+		}
+	case actionAuth:
+		p.handleAuth()
+	}
+	return nil
+}
+
+func (p *ablyProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	if e, ok := e.(*Event); ok && e.msg != nil {
+		return ws.KindInternal, p.handleMessage(e.msg)
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *ablyProtocol) attachFrame(channel string, serial string) []byte {
+	if p.channel(channel) == nil || !p.isConnected() {
+		return nil
+	}
+	return p.marshal(&ProtocolMessage{
+		Action: actionAttach,
+		Channel: channel,
+		ChannelSerial: serial,
+	})
+}
+
+func (p *ablyProtocol) SubscribeFrame(channel string) []byte {
+	return p.attachFrame(channel, "")
+}
+
+// Attach from the last channel serial.
+func (p *ablyProtocol) ResumeFrame(channel string, lastId string) []byte {
+	return p.attachFrame(channel, lastId)
+}
+
+// Channel serial of a message, for resuming the channel after a reconnect.
+func (p *ablyProtocol) MessageId(e ws.Event) string {
+	if e, ok := e.(*Event); ok {
+		return e.serial
+	}
+	return ""
+}
+
+func (p *ablyProtocol) UnsubscribeFrame(channel string) []byte {
+	if !p.isConnected() {
+		return nil
+	}
+	return p.marshal(&ProtocolMessage{
+		Action: actionDetach,
+		Channel: channel,
+	})
+}
+
+func (p *ablyProtocol) PingFrame() []byte {
+	return p.marshal(&ProtocolMessage{Action: actionHeartbeat})
+}
+
+func (p *ablyProtocol) PongFrame() []byte {
+	return nil
+}
+
+func (p *ablyProtocol) NewChannel(name string) ws.Channel {
+	return newChannel(name, p)
+}
@@ -0,0 +1,89 @@
+package ably
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Message or presence message received on a channel.  Presence events are
+// named "presence.<action>".
+type Event struct {
+	Channel    string
+	Name       string
+	Data       interface{}
+	Id         string
+	ClientId   string
+	Timestamp  int64
+	serial     string  // channel serial
+	msg        *ProtocolMessage  // not sent to handlers
+}
+
+func (e *Event) GetEvent() string {
+	return e.Name
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Name = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Channel
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Data
+}
+
+func (e *Event) SetData(data interface{}) {
+	e.Data = data
+}
+
+func (e *Event) GetDataString() string {
+	// Normalize Data as a string value.
+	switch data := e.Data.(type) {
+	case string:
+		return data
+	case []byte:
+		return string(data)
+	default:
+		buf, err := json.Marshal(e.Data)
+		if err != nil {
+			log.Fatal("JSON Marshaller failed:", err)
+		}
+		return string(buf)
+	}
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Data = data
+}
+
+func messageEvent(channel string, m *Message) *Event {
+	return &Event{
+		Channel: channel,
+		Name: m.Name,
+		Data: decodeData(m.Data, m.Encoding),
+		Id: m.Id,
+		ClientId: m.ClientId,
+		Timestamp: m.Timestamp,
+	}
+}
+
+func presenceEvent(channel string, m *PresenceMessage) *Event {
+	action := "unknown"
+	if m.Action >= 0 && m.Action < len(presenceActions) {
+		action = presenceActions[m.Action]
+	}
+	return &Event{
+		Channel: channel,
+		Name: "presence." + action,
+		Data: decodeData(m.Data, m.Encoding),
+		Id: m.Id,
+		ClientId: m.ClientId,
+		Timestamp: m.Timestamp,
+	}
+}
@@ -0,0 +1,157 @@
+package ably
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ProtocolMessage actions
+const (
+	actionHeartbeat = iota
+	actionAck
+	actionNack
+	actionConnect
+	actionConnected
+	actionDisconnect
+	actionDisconnected
+	actionClose
+	actionClosed
+	actionError
+	actionAttach
+	actionAttached
+	actionDetach
+	actionDetached
+	actionPresence
+	actionMessage
+	actionSync
+	actionAuth
+)
+
+// PresenceMessage actions
+var presenceActions = []string{"absent", "present", "enter", "leave", "update"}
+
+const (
+	presenceEnter = 2
+	presenceLeave = 3
+	presenceUpdate = 4
+)
+
+// ProtocolMessage flags
+const (
+	flagResumed = 1 << 2
+)
+
+type ErrorInfo struct {
+	Code        int `json:"code"`
+	StatusCode  int `json:"statusCode"`
+	Message     string `json:"message"`
+}
+
+func (e *ErrorInfo) Error() string {
+	return fmt.Sprintf("ably: error %d: %s", e.Code, e.Message)
+}
+
+func (e *ErrorInfo) tokenError() bool {
+	return 40140 <= e.Code && e.Code < 40150
+}
+
+type Message struct {
+	Id         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	ClientId   string `json:"clientId,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Encoding   string `json:"encoding,omitempty"`
+	Timestamp  int64 `json:"timestamp,omitempty"`
+}
+
+type PresenceMessage struct {
+	Id         string `json:"id,omitempty"`
+	Action     int `json:"action"`
+	ClientId   string `json:"clientId,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Encoding   string `json:"encoding,omitempty"`
+	Timestamp  int64 `json:"timestamp,omitempty"`
+}
+
+type ConnectionDetails struct {
+	ClientId            string `json:"clientId,omitempty"`
+	ConnectionKey       string `json:"connectionKey"`
+	MaxIdleInterval     int64 `json:"maxIdleInterval"`
+	ConnectionStateTtl  int64 `json:"connectionStateTtl"`
+}
+
+type ProtocolMessage struct {
+	Action             int `json:"action"`
+	Flags              int64 `json:"flags,omitempty"`
+	Id                 string `json:"id,omitempty"`
+	Channel            string `json:"channel,omitempty"`
+	ChannelSerial      string `json:"channelSerial,omitempty"`
+	ConnectionId       string `json:"connectionId,omitempty"`
+	MsgSerial          int64 `json:"msgSerial"`
+	Timestamp          int64 `json:"timestamp,omitempty"`
+	Count              int `json:"count,omitempty"`
+	Error              *ErrorInfo `json:"error,omitempty"`
+	Messages           []*Message `json:"messages,omitempty"`
+	Presence           []*PresenceMessage `json:"presence,omitempty"`
+	ConnectionDetails  *ConnectionDetails `json:"connectionDetails,omitempty"`
+	Auth               *struct {
+		AccessToken string `json:"accessToken"`
+	} `json:"auth,omitempty"`
+}
+
+// Undo the data encodings, last encoding first.
+func decodeData(data interface{}, encoding string) interface{} {
+	if encoding == "" {
+		return data
+	}
+	encs := strings.Split(encoding, "/")
+	for i := len(encs) - 1; i >= 0; i-- {
+		switch encs[i] {
+		case "json":
+			s, ok := data.(string)
+			if !ok {
+				return data
+			}
+			var v interface{}
+			if err := json.Unmarshal([]byte(s), &v); err != nil {
+				return data
+			}
+			data = v
+		case "base64":
+			s, ok := data.(string)
+			if !ok {
+				return data
+			}
+			buf, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return data
+			}
+			data = buf
+		case "utf-8":
+			if buf, ok := data.([]byte); ok {
+				data = string(buf)
+			}
+		default:
+			// unsupported encoding (e.g. cipher)
+			return data
+		}
+	}
+	return data
+}
+
+// Encode data for sending, binary is sent as base64 and objects as json.
+func encodeData(data interface{}) (interface{}, string) {
+	switch data := data.(type) {
+	case nil, string:
+		return data, ""
+	case []byte:
+		return base64.StdEncoding.EncodeToString(data), "base64"
+	}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Sprint(data), ""
+	}
+	return string(buf), "json"
+}
@@ -0,0 +1,100 @@
+package actioncable
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// delay before reconnecting after a server requested disconnect.
+	RECONNECT_DELAY = time.Second * 3
+)
+
+type CableClient struct {
+	*ws.ProtocolClient
+}
+
+func (c *CableClient) SendCommand(cmd *Command) {
+	buf, err := json.Marshal(cmd)
+	if err != nil {
+		log.Println("Error sending command:", err)
+		return
+	}
+	c.SendMessage(buf)
+}
+
+// Build a channel identifier from the channel class name and params.
+func Identifier(channel string, params map[string]interface{}) string {
+	id := make(map[string]interface{}, len(params) + 1)
+	for k, v := range params {
+		id[k] = v
+	}
+	id["channel"] = channel
+	buf, err := json.Marshal(id)
+	if err != nil {
+		log.Fatal("Error encoding identifier:", err)
+	}
+	return string(buf)
+}
+
+// Subscribe to a channel class with params.
+func (c *CableClient) SubscribeParams(channel string, params map[string]interface{}) *Channel {
+	identifier := Identifier(channel, params)
+	if ch, ok := c.FindChannel(identifier).(*Channel); ok {
+		return ch
+	}
+	// create a new channel.
+	ch := NewChannel(identifier, c)
+	c.AddChannel(identifier, ch)
+	return ch
+}
+
+// Subscribe to a channel class name or a JSON identifier.
+func (c *CableClient) Subscribe(channel string) ws.Channel {
+	if strings.HasPrefix(channel, "{") {
+		return c.ProtocolClient.Subscribe(channel)
+	}
+	return c.SubscribeParams(channel, nil)
+}
+
+func (c *CableClient) Unsubscribe(channel string) {
+	if !strings.HasPrefix(channel, "{") {
+		channel = Identifier(channel, nil)
+	}
+	c.ProtocolClient.Unsubscribe(channel)
+}
+
+type CableConfig struct {
+	ws.Config
+}
+
+var (
+	// The server pings every 3 seconds, the connection is stale after 2 missed pings.
+	DefaultCable = CableConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 6,
+			PingTimeout:     time.Second * 3,
+			Subprotocols:    []string{"actioncable-v1-json", "actioncable-unsupported"},
+		},
+	}
+)
+
+func (cf CableConfig) NewCableUrl(cableUrl string) (*CableClient, error) {
+	u, err := url.Parse(cableUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &CableClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, &cableProtocol{}),
+	}, nil
+}
+
+func NewCableUrl(cableUrl string) (*CableClient, error) {
+	return DefaultCable.NewCableUrl(cableUrl)
+}
@@ -0,0 +1,85 @@
+package actioncable
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler, "actioncable-v1-json")
+}
+
+func readCommand(t *testing.T, conn *websocket.Conn) *Command {
+	cmd := &Command{}
+	if err := conn.ReadJSON(cmd); err != nil {
+		t.Error(err)
+		return nil
+	}
+	return cmd
+}
+
+func TestIdentifier(t *testing.T) {
+	id := Identifier("ChatChannel", map[string]interface{}{"room": "a"})
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(id), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed["channel"] != "ChatChannel" || parsed["room"] != "a" {
+		t.Fatalf("bad identifier: %s", id)
+	}
+	// map keys are sorted, so the identifier is stable.
+	if id != Identifier("ChatChannel", map[string]interface{}{"room": "a"}) {
+		t.Fatal("identifier isn't stable")
+	}
+}
+
+func TestSubscribeAndPerform(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"welcome"}`))
+		sub := readCommand(t, conn)
+		if sub == nil || sub.Command != "subscribe" {
+			t.Errorf("expected subscribe, got %+v", sub)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`not json`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping","message":1}`))
+		conn.WriteJSON(&Event{Type: "confirm_subscription", Identifier: sub.Identifier})
+		conn.WriteJSON(&Event{Identifier: sub.Identifier, Message: map[string]interface{}{"text": "hi"}})
+		msg := readCommand(t, conn)
+		if msg == nil || msg.Command != "message" || msg.Identifier != sub.Identifier || !strings.Contains(msg.Data, `"action":"speak"`) {
+			t.Errorf("expected message, got %+v", msg)
+		}
+		<-done
+	})
+	defer close(done)
+	client, err := NewCableUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 3)
+	ch := client.SubscribeParams("ChatChannel", map[string]interface{}{"room": "a"})
+	ch.BindAllFunc(func(e ws.Event) {
+		got <- e
+	})
+	for _, expected := range []string{"confirm_subscription", "message"} {
+		select {
+		case e := <-got:
+			if e.GetEvent() != expected {
+				t.Fatalf("expected %s, got %s", expected, e.GetEvent())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for", expected)
+		}
+	}
+	if err := ch.Perform("speak", map[string]interface{}{"text": "yo"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,43 @@
+package actioncable
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+)
+
+type Channel struct {
+	*ws.PublicChannel
+	client      *ws.ProtocolClient
+	Identifier  string
+}
+
+// Call a channel action on the server.
+func (c *Channel) Perform(action string, data map[string]interface{}) error {
+	msg := make(map[string]interface{}, len(data) + 1)
+	for k, v := range data {
+		msg[k] = v
+	}
+	msg["action"] = action
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.client.SendEvent(&Event{
+		Identifier: c.Identifier,
+		Message: string(buf),
+	})
+	return nil
+}
+
+func newChannel(identifier string, client *ws.ProtocolClient) *Channel {
+	return &Channel{
+		PublicChannel: ws.NewPublicChannel(identifier, client),
+		client: client,
+		Identifier: identifier,
+	}
+}
+
+func NewChannel(identifier string, client *CableClient) *Channel {
+	return newChannel(identifier, client.ProtocolClient)
+}
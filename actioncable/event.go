@@ -0,0 +1,73 @@
+package actioncable
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Message from the server.  Broadcasts have no type and are delivered as
+// "message" events.
+type Event struct {
+	Type        string `json:"type,omitempty"`
+	Identifier  string `json:"identifier,omitempty"`
+	Message     interface{} `json:"message,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Reconnect   *bool `json:"reconnect,omitempty"`
+}
+
+func (e *Event) GetEvent() string {
+	if e.Type == "" {
+		return "message"
+	}
+	return e.Type
+}
+
+// Only broadcasts are application events.
+func (e *Event) Internal() bool {
+	return e.Type != ""
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Type = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Identifier
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Identifier = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Message
+}
+
+func (e *Event) SetData(data interface{}) {
+	e.Message = data
+}
+
+func (e *Event) GetDataString() string {
+	// Normalize Message as a string value.
+	switch e.Message.(type) {
+	case string:
+		return e.Message.(string)
+	default:
+		buf, err := json.Marshal(e.Message)
+		if err != nil {
+			log.Fatal("JSON Marshaller failed:", err)
+		}
+		return string(buf)
+	}
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Message = data
+}
+
+// Command sent to the server.  Identifier and Data are JSON encoded strings.
+type Command struct {
+	Command     string `json:"command"`
+	Identifier  string `json:"identifier"`
+	Data        string `json:"data,omitempty"`
+}
@@ -0,0 +1,101 @@
+package actioncable
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"log"
+)
+
+// Codec for the actioncable-v1-json protocol.
+type cableProtocol struct {
+	client  *ws.ProtocolClient
+}
+
+func (p *cableProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *cableProtocol) HandshakeFrame() []byte {
+	// server sends welcome.
+	return nil
+}
+
+func (p *cableProtocol) encodeCommand(cmd *Command) []byte {
+	buf, _ := json.Marshal(cmd)
+	return buf
+}
+
+func (p *cableProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	return json.Marshal(&Command{
+		Command: "message",
+		Identifier: e.GetChannel(),
+		Data: e.GetDataString(),
+	})
+}
+
+func (p *cableProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	event := &Event{}
+	if err := json.Unmarshal(msg, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (p *cableProtocol) handleDisconnect(event *Event) error {
+	reconnect := event.Reconnect == nil || *event.Reconnect
+	log.Println("ActionCable disconnect: reason:", event.Reason, ", reconnect:", reconnect)
+	if !reconnect {
+		return ws.NewError("Disconnected: " + event.Reason, false, false, 0)
+	}
+	return ws.NewError("Disconnected: " + event.Reason, false, true, RECONNECT_DELAY)
+}
+
+func (p *cableProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	event := e.(*Event)
+	switch event.Type {
+	case "ping":
+		// server heartbeat, handled as activity.
+		return ws.KindInternal, nil
+	case "welcome":
+		return ws.KindConnected, nil
+	case "disconnect":
+		return ws.KindEvent, p.handleDisconnect(event)
+	case "confirm_subscription":
+		return ws.KindSubscribed, nil
+	case "reject_subscription":
+		log.Println("ActionCable subscription rejected:", event.Identifier)
+		return ws.KindUnsubscribed, nil
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *cableProtocol) SubscribeFrame(identifier string) []byte {
+	return p.encodeCommand(&Command{
+		Command: "subscribe",
+		Identifier: identifier,
+	})
+}
+
+func (p *cableProtocol) UnsubscribeFrame(identifier string) []byte {
+	return p.encodeCommand(&Command{
+		Command: "unsubscribe",
+		Identifier: identifier,
+	})
+}
+
+// server sends the pings, nothing to do but wait for the ping timeout.
+func (p *cableProtocol) SendPing() {
+}
+
+func (p *cableProtocol) PingFrame() []byte {
+	return nil
+}
+
+func (p *cableProtocol) PongFrame() []byte {
+	return nil
+}
+
+func (p *cableProtocol) NewChannel(identifier string) ws.Channel {
+	return newChannel(identifier, p.client)
+}
@@ -0,0 +1,137 @@
+package centrifuge
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+)
+
+const (
+	// extra time to wait for a server ping.
+	MAX_SERVER_PING_DELAY = time.Second * 10
+)
+
+var (
+	ErrDisconnected = errors.New("centrifuge: disconnected before reply")
+)
+
+type CentrifugeClient struct {
+	*ws.ProtocolClient
+	proto  *centrifugeProtocol
+}
+
+// Send a command and wait for the result.
+func (c *CentrifugeClient) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	type result struct {
+		res json.RawMessage
+		err error
+	}
+	done := make(chan result, 1)
+	id := c.proto.send(method, params, func(r *reply) error {
+		if r == nil {
+			done <- result{err: ErrDisconnected}
+		} else if r.Error != nil {
+			done <- result{err: r.Error}
+		} else {
+			done <- result{res: r.Result}
+		}
+		return nil
+	})
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-ctx.Done():
+		c.proto.forget(id)
+		return nil, ctx.Err()
+	}
+}
+
+// Publish data to a channel and wait for the server to accept it.
+func (c *CentrifugeClient) Publish(ctx context.Context, channel string, data interface{}) error {
+	_, err := c.call(ctx, "publish", map[string]interface{}{
+		"channel": channel,
+		"data": data,
+	})
+	return err
+}
+
+// Get the clients subscribed to a channel.
+func (c *CentrifugeClient) Presence(ctx context.Context, channel string) (map[string]*ClientInfo, error) {
+	raw, err := c.call(ctx, "presence", map[string]string{"channel": channel})
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Presence map[string]*ClientInfo `json:"presence"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return res.Presence, nil
+}
+
+// Get the publication history of a channel.
+func (c *CentrifugeClient) History(ctx context.Context, channel string, opts *HistoryOptions) (*HistoryResult, error) {
+	params := struct {
+		Channel string `json:"channel"`
+		*HistoryOptions
+	}{
+		Channel: channel,
+		HistoryOptions: opts,
+	}
+	raw, err := c.call(ctx, "history", params)
+	if err != nil {
+		return nil, err
+	}
+	res := &HistoryResult{}
+	if err := json.Unmarshal(raw, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type CentrifugeConfig struct {
+	ws.Config
+	Name                  string
+	// Connection JWT, GetToken is used to get a new token when it expires.
+	Token                 string
+	GetToken              func() (string, error)
+	// Token for private channels.
+	GetSubscriptionToken  func(channel string) (string, error)
+}
+
+var (
+	DefaultCentrifuge = CentrifugeConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 120,
+			PingTimeout:     time.Second * 30,
+		},
+		Name:            "websocket-client-go",
+	}
+)
+
+// Connect to a Centrifugo websocket endpoint (e.g. "wss://host/connection/websocket").
+func (cf CentrifugeConfig) NewCentrifugeUrl(centrifugeUrl string) (*CentrifugeClient, error) {
+	u, err := url.Parse(centrifugeUrl)
+	if err != nil {
+		return nil, err
+	}
+	proto := &centrifugeProtocol{
+		cf: cf,
+		token: cf.Token,
+		pending: make(map[uint32]replyFn),
+	}
+	return &CentrifugeClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		proto: proto,
+	}, nil
+}
+
+func NewCentrifugeUrl(centrifugeUrl string) (*CentrifugeClient, error) {
+	return DefaultCentrifuge.NewCentrifugeUrl(centrifugeUrl)
+}
@@ -0,0 +1,133 @@
+package centrifuge
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(n int, conn *websocket.Conn)) string {
+	var conns int32
+	return wstest.Server(t, func(conn *websocket.Conn) {
+		handler(int(atomic.AddInt32(&conns, 1)), conn)
+	})
+}
+
+func readCommand(t *testing.T, conn *websocket.Conn) map[string]json.RawMessage {
+	var cmd map[string]json.RawMessage
+	if err := conn.ReadJSON(&cmd); err != nil {
+		t.Error(err)
+		return nil
+	}
+	return cmd
+}
+
+func TestReplyDecode(t *testing.T) {
+	var r reply
+	if err := json.Unmarshal([]byte(`{"id":3,"subscribe":{"recoverable":true}}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Id != 3 || string(r.Result) != `{"recoverable":true}` {
+		t.Fatalf("bad reply: %+v", r)
+	}
+	r = reply{}
+	if err := json.Unmarshal([]byte(`{"push":{"channel":"a","pub":{"data":1,"offset":5}}}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Push == nil || r.Push.Pub == nil || r.Push.Pub.Offset != 5 {
+		t.Fatalf("bad push: %+v", r)
+	}
+	buf, _ := json.Marshal(&command{Id: 1, method: "publish", params: map[string]int{"a": 1}})
+	if string(buf) != `{"id":1,"publish":{"a":1}}` {
+		t.Fatalf("bad command: %s", buf)
+	}
+}
+
+func TestSubscribeRecover(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(n int, conn *websocket.Conn) {
+		connect := readCommand(t, conn)
+		if connect == nil || connect["connect"] == nil {
+			t.Errorf("expected connect, got %v", connect)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"id":`+string(connect["id"])+`,"connect":{"client":"c","ping":25}}`))
+		sub := readCommand(t, conn)
+		if sub == nil || sub["subscribe"] == nil {
+			t.Errorf("expected subscribe, got %v", sub)
+			return
+		}
+		var req subscribeRequest
+		json.Unmarshal(sub["subscribe"], &req)
+		switch n {
+		case 1:
+			// disconnect before the subscribe reply.
+			return
+		case 2:
+			if req.Recover {
+				t.Errorf("unexpected recover: %+v", req)
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(`not json`))
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"id":`+string(sub["id"])+`,"subscribe":{"recoverable":true,"epoch":"e","offset":2,"publications":[{"data":"missed","offset":2}]}}`+"\n"+
+				`{"push":{"channel":"news","pub":{"data":"live","offset":3}}}`))
+			// disconnect after the publications.
+			return
+		}
+		if !req.Recover || req.Offset != 3 || req.Epoch != "e" {
+			t.Errorf("expected recover from 3:e, got %+v", req)
+		}
+		// history is gone.
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"id":`+string(sub["id"])+`,"subscribe":{"recoverable":true,"epoch":"e2","offset":10}}`))
+		<-done
+	})
+	defer close(done)
+	client, err := NewCentrifugeUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 3)
+	// recovered publications go through the client's handlers too.
+	client.BindFunc("publication", func(e ws.Event) {
+		got <- e
+	})
+	client.BindFunc(ws.GAP_EVENT, func(e ws.Event) {
+		got <- e
+	})
+	sub := client.Subscribe("news").(*Subscription)
+	for _, expected := range []string{"missed", "live", "3:e"} {
+		select {
+		case e := <-got:
+			if e.GetChannel() != "news" || e.GetDataString() != expected {
+				t.Fatalf("expected %s, got %s: %s", expected, e.GetChannel(), e.GetDataString())
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for", expected)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for sub.Position() != (StreamPosition{Offset: 10, Epoch: "e2"}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("bad position: %+v", sub.Position())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	pos, ok := parsePosition(formatPosition(StreamPosition{Offset: 7, Epoch: "a:b"}))
+	if !ok || pos.Offset != 7 || pos.Epoch != "a:b" {
+		t.Fatalf("bad position: %+v", pos)
+	}
+	if _, ok := parsePosition("x:e"); ok {
+		t.Fatal("expected bad offset")
+	}
+	if _, ok := parsePosition(""); ok {
+		t.Fatal("expected missing position")
+	}
+}
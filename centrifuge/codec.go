@@ -0,0 +1,399 @@
+package centrifuge
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+type replyFn func(*reply) error
+
+// Codec for the Centrifuge JSON protocol.
+type centrifugeProtocol struct {
+	client        *ws.ProtocolClient
+	cf            CentrifugeConfig
+	mu            sync.Mutex
+	nextId        uint32
+	pending       map[uint32]replyFn
+	token         string
+	refreshTimer  *time.Timer
+	pong          bool
+}
+
+func (p *centrifugeProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+// Encode a command, fn gets the reply (nil if disconnected first).
+func (p *centrifugeProtocol) command(method string, params interface{}, fn replyFn) (uint32, []byte) {
+	p.mu.Lock()
+	p.nextId++
+	cmd := &command{
+		Id: p.nextId,
+		method: method,
+		params: params,
+	}
+	if fn != nil {
+		p.pending[cmd.Id] = fn
+	}
+	p.mu.Unlock()
+	buf, err := json.Marshal(cmd)
+	if err != nil {
+		log.Println("Error encoding command:", method, err)
+		p.forget(cmd.Id)
+		return cmd.Id, nil
+	}
+	return cmd.Id, buf
+}
+
+func (p *centrifugeProtocol) send(method string, params interface{}, fn replyFn) uint32 {
+	id, buf := p.command(method, params, fn)
+	if buf != nil {
+		p.client.SendMessage(buf)
+	}
+	return id
+}
+
+func (p *centrifugeProtocol) forget(id uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+func (p *centrifugeProtocol) getToken() (string, error) {
+	p.mu.Lock()
+	token := p.token
+	p.mu.Unlock()
+	if token != "" || p.cf.GetToken == nil {
+		return token, nil
+	}
+	token, err := p.cf.GetToken()
+	if err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	p.token = token
+	p.mu.Unlock()
+	return token, nil
+}
+
+func (p *centrifugeProtocol) subscriptionToken(channel string) (string, error) {
+	if p.cf.GetSubscriptionToken == nil {
+		return "", nil
+	}
+	return p.cf.GetSubscriptionToken(channel)
+}
+
+func (p *centrifugeProtocol) Opened() bool {
+	// wait for the connect reply.
+	return false
+}
+
+func (p *centrifugeProtocol) Closed() {
+	p.mu.Lock()
+	if p.refreshTimer != nil {
+		p.refreshTimer.Stop()
+		p.refreshTimer = nil
+	}
+	pending := p.pending
+	p.pending = make(map[uint32]replyFn)
+	p.mu.Unlock()
+	// fail all commands waiting for a reply.
+	for _, fn := range pending {
+		p.client.SafeCall("", "reply", func() {
+			fn(nil)
+		})
+	}
+}
+
+func (p *centrifugeProtocol) HandshakeFrame() []byte {
+	token, err := p.getToken()
+	if err != nil {
+		log.Println("Failed to get connection token:", err)
+	}
+	_, buf := p.command("connect", &connectRequest{
+		Token: token,
+		Name: p.cf.Name,
+	}, p.handleConnectReply)
+	return buf
+}
+
+func (p *centrifugeProtocol) handleConnectReply(r *reply) error {
+	if r == nil {
+		return nil
+	}
+	if r.Error != nil {
+		log.Println("Centrifuge connect failed:", r.Error)
+		if r.Error.Code == errTokenExpired {
+			// get a new token and reconnect.
+			p.mu.Lock()
+			p.token = ""
+			p.mu.Unlock()
+			return ws.ErrReconnect
+		}
+		if r.Error.Temporary {
+			return ws.ErrDelayReconnect
+		}
+		return ws.NewError(r.Error.Error(), false, false, 0)
+	}
+	var res connectResult
+	if err := json.Unmarshal(r.Result, &res); err != nil {
+		log.Println("Bad connect result:", err)
+		return ws.ErrDelayReconnect
+	}
+	p.mu.Lock()
+	p.pong = res.Pong
+	p.mu.Unlock()
+	// server sends pings every res.Ping seconds.
+	if res.Ping > 0 {
+		p.client.Socket().SetActivityTimeout(time.Duration(res.Ping) * time.Second + MAX_SERVER_PING_DELAY)
+	}
+	if res.Expires {
+		p.scheduleRefresh(res.Ttl)
+	}
+	// subscribe to channels.
+	p.client.Connected()
+	return nil
+}
+
+func (p *centrifugeProtocol) scheduleRefresh(ttl uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.refreshTimer != nil {
+		p.refreshTimer.Stop()
+	}
+	p.refreshTimer = time.AfterFunc(time.Duration(ttl) * time.Second, p.refresh)
+}
+
+// Refresh the connection token before it expires.
+func (p *centrifugeProtocol) refresh() {
+	if p.cf.GetToken == nil {
+		return
+	}
+	token, err := p.cf.GetToken()
+	if err != nil {
+		log.Println("Failed to refresh connection token:", err)
+		return
+	}
+	p.mu.Lock()
+	p.token = token
+	p.mu.Unlock()
+	p.send("refresh", map[string]string{"token": token}, func(r *reply) error {
+		if r == nil {
+			return nil
+		}
+		if r.Error != nil {
+			log.Println("Centrifuge token refresh failed:", r.Error)
+			return ws.ErrDelayReconnect
+		}
+		var res refreshResult
+		if err := json.Unmarshal(r.Result, &res); err != nil {
+			log.Println("Bad refresh result:", err)
+			return nil
+		}
+		if res.Expires {
+			p.scheduleRefresh(res.Ttl)
+		}
+		return nil
+	})
+}
+
+func disconnectError(code uint32, reason string) error {
+	log.Println("Centrifuge disconnect: code:", code, ", reason:", reason)
+	switch {
+	case 3500 <= code && code <= 3999, 4500 <= code && code <= 4999:
+		return ws.NewError("Disconnected: " + reason, false, false, 0)
+	}
+	return ws.ErrDelayReconnect
+}
+
+func (p *centrifugeProtocol) pushEvent(push *push) (*Event, error) {
+	switch {
+	case push.Pub != nil:
+		return publicationEvent(push.Channel, push.Pub), nil
+	case push.Join != nil:
+		return &Event{Channel: push.Channel, Event: "join", Info: &push.Join.Info}, nil
+	case push.Leave != nil:
+		return &Event{Channel: push.Channel, Event: "leave", Info: &push.Leave.Info}, nil
+	case push.Message != nil:
+		return &Event{Event: "message", Data: push.Message.Data}, nil
+	case push.Unsubscribe != nil:
+		return &Event{
+			Channel: push.Channel,
+			Event: "unsubscribed",
+			Data: push.Unsubscribe.Reason,
+			code: push.Unsubscribe.Code,
+		}, nil
+	case push.Disconnect != nil:
+		return nil, disconnectError(push.Disconnect.Code, push.Disconnect.Reason)
+	}
+	return nil, nil
+}
+
+func (p *centrifugeProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	_, buf := p.command("publish", map[string]interface{}{
+		"channel": e.GetChannel(),
+		"data": e.GetData(),
+	}, nil)
+	return buf, nil
+}
+
+func (p *centrifugeProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	r := &reply{}
+	if err := json.Unmarshal(msg, r); err != nil {
+		return nil, err
+	}
+	return p.replyEvent(r)
+}
+
+func (p *centrifugeProtocol) replyEvent(r *reply) (ws.Event, error) {
+	if r.Push == nil {
+		return &Event{reply: r}, nil
+	}
+	e, err := p.pushEvent(r.Push)
+	if e == nil {
+		return nil, err
+	}
+	return e, err
+}
+
+// Replies can be batched as newline delimited JSON.
+func (p *centrifugeProtocol) DecodeEvents(msg []byte) ([]ws.Event, error) {
+	var events []ws.Event
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	for {
+		r := &reply{}
+		if err := dec.Decode(r); err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return events, err
+		}
+		e, err := p.replyEvent(r)
+		if e != nil {
+			events = append(events, e)
+		}
+		if err != nil {
+			return events, err
+		}
+	}
+}
+
+func (p *centrifugeProtocol) handleReply(r *reply) error {
+	if r.Id == 0 {
+		// server ping
+		p.mu.Lock()
+		pong := p.pong
+		p.mu.Unlock()
+		if pong {
+			p.client.SendMessage([]byte("{}"))
+		}
+		return nil
+	}
+	p.mu.Lock()
+	fn := p.pending[r.Id]
+	delete(p.pending, r.Id)
+	p.mu.Unlock()
+	var err error
+	if fn != nil {
+		p.client.SafeCall("", "reply", func() {
+			err = fn(r)
+		})
+	}
+	return err
+}
+
+func (p *centrifugeProtocol) Classify(event ws.Event) (ws.MessageKind, error) {
+	e := event.(*Event)
+	if e.reply != nil {
+		return ws.KindInternal, p.handleReply(e.reply)
+	}
+	if e.Event == "unsubscribed" {
+		// codes below 2500 mean the server won't allow a resubscribe.
+		if e.code >= 2500 {
+			p.client.SendSubscribe(e.Channel)
+		}
+		return ws.KindUnsubscribed, nil
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *centrifugeProtocol) subscription(channel string) *Subscription {
+	sub, _ := p.client.FindChannel(channel).(*Subscription)
+	return sub
+}
+
+func (p *centrifugeProtocol) subscribe(channel string, since *StreamPosition) []byte {
+	sub := p.subscription(channel)
+	if sub == nil {
+		return nil
+	}
+	token, err := p.subscriptionToken(channel)
+	if err != nil {
+		log.Println("Failed to get subscription token:", channel, err)
+		return nil
+	}
+	req := &subscribeRequest{
+		Channel: channel,
+		Token: token,
+	}
+	if since != nil {
+		req.Recover = true
+		req.Offset = since.Offset
+		req.Epoch = since.Epoch
+	}
+	_, buf := p.command("subscribe", req, sub.handleSubscribeReply)
+	return buf
+}
+
+func (p *centrifugeProtocol) SubscribeFrame(channel string) []byte {
+	return p.subscribe(channel, nil)
+}
+
+// Recover the publications after the position.
+func (p *centrifugeProtocol) ResumeFrame(channel string, lastId string) []byte {
+	pos, ok := parsePosition(lastId)
+	if !ok {
+		return p.subscribe(channel, nil)
+	}
+	return p.subscribe(channel, &pos)
+}
+
+// Position of a publication, for recovering it after a reconnect.
+func (p *centrifugeProtocol) MessageId(event ws.Event) string {
+	e, ok := event.(*Event)
+	if !ok || e.Event != "publication" {
+		return ""
+	}
+	sub := p.subscription(e.Channel)
+	if sub == nil {
+		return ""
+	}
+	return sub.positionId(e.Offset)
+}
+
+func (p *centrifugeProtocol) UnsubscribeFrame(channel string) []byte {
+	_, buf := p.command("unsubscribe", map[string]string{"channel": channel}, nil)
+	return buf
+}
+
+// server sends the pings, nothing to do but wait for the ping timeout.
+func (p *centrifugeProtocol) SendPing() {
+}
+
+func (p *centrifugeProtocol) PingFrame() []byte {
+	return nil
+}
+
+func (p *centrifugeProtocol) PongFrame() []byte {
+	return nil
+}
+
+func (p *centrifugeProtocol) NewChannel(channel string) ws.Channel {
+	return newSubscription(channel, p)
+}
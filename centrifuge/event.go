@@ -0,0 +1,68 @@
+package centrifuge
+
+import (
+	"encoding/json"
+	"log"
+)
+
+type Event struct {
+	Channel  string
+	Event    string
+	Data     interface{}
+	Offset   uint64
+	Info     *ClientInfo
+	code     uint32  // unsubscribe code
+	reply    *reply  // command reply, not sent to handlers
+}
+
+func (e *Event) GetEvent() string {
+	return e.Event
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Event = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Channel
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Data
+}
+
+func (e *Event) SetData(data interface{}) {
+	e.Data = data
+}
+
+func (e *Event) GetDataString() string {
+	// Normalize Data as a string value.
+	switch e.Data.(type) {
+	case string:
+		return e.Data.(string)
+	default:
+		buf, err := json.Marshal(e.Data)
+		if err != nil {
+			log.Fatal("JSON Marshaller failed:", err)
+		}
+		return string(buf)
+	}
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Data = data
+}
+
+func publicationEvent(channel string, pub *Publication) *Event {
+	return &Event{
+		Channel: channel,
+		Event: "publication",
+		Data: pub.Data,
+		Offset: pub.Offset,
+		Info: pub.Info,
+	}
+}
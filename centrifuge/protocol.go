@@ -0,0 +1,173 @@
+package centrifuge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type ClientInfo struct {
+	User      string `json:"user"`
+	Client    string `json:"client"`
+	ConnInfo  json.RawMessage `json:"conn_info,omitempty"`
+	ChanInfo  json.RawMessage `json:"chan_info,omitempty"`
+}
+
+type Publication struct {
+	Data    interface{} `json:"data"`
+	Offset  uint64 `json:"offset,omitempty"`
+	Info    *ClientInfo `json:"info,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+type StreamPosition struct {
+	Offset  uint64 `json:"offset"`
+	Epoch   string `json:"epoch"`
+}
+
+// Positions are tracked as "<offset>:<epoch>" message ids.
+func formatPosition(pos StreamPosition) string {
+	return strconv.FormatUint(pos.Offset, 10) + ":" + pos.Epoch
+}
+
+func parsePosition(id string) (StreamPosition, bool) {
+	i := strings.IndexByte(id, ':')
+	if i < 0 {
+		return StreamPosition{}, false
+	}
+	offset, err := strconv.ParseUint(id[:i], 10, 64)
+	if err != nil {
+		return StreamPosition{}, false
+	}
+	return StreamPosition{Offset: offset, Epoch: id[i+1:]}, true
+}
+
+type ReplyError struct {
+	Code       uint32 `json:"code"`
+	Message    string `json:"message"`
+	Temporary  bool `json:"temporary,omitempty"`
+}
+
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("centrifuge: error %d: %s", e.Code, e.Message)
+}
+
+const (
+	errTokenExpired = 109
+)
+
+type command struct {
+	Id      uint32 `json:"id"`
+	method  string
+	params  interface{}
+}
+
+func (c *command) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"id": c.Id,
+		c.method: c.params,
+	})
+}
+
+type connectRequest struct {
+	Token  string `json:"token,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type connectResult struct {
+	Client   string `json:"client"`
+	Version  string `json:"version"`
+	Expires  bool `json:"expires"`
+	Ttl      uint32 `json:"ttl"`
+	Ping     uint32 `json:"ping"`
+	Pong     bool `json:"pong"`
+}
+
+type refreshResult struct {
+	Expires  bool `json:"expires"`
+	Ttl      uint32 `json:"ttl"`
+}
+
+type subscribeRequest struct {
+	Channel  string `json:"channel"`
+	Token    string `json:"token,omitempty"`
+	Recover  bool `json:"recover,omitempty"`
+	Offset   uint64 `json:"offset,omitempty"`
+	Epoch    string `json:"epoch,omitempty"`
+}
+
+type subscribeResult struct {
+	Recoverable   bool `json:"recoverable"`
+	Epoch         string `json:"epoch"`
+	Offset        uint64 `json:"offset"`
+	Recovered     bool `json:"recovered"`
+	Publications  []*Publication `json:"publications"`
+}
+
+type HistoryOptions struct {
+	Limit    int32 `json:"limit,omitempty"`
+	Since    *StreamPosition `json:"since,omitempty"`
+	Reverse  bool `json:"reverse,omitempty"`
+}
+
+type HistoryResult struct {
+	Publications  []*Publication `json:"publications"`
+	Epoch         string `json:"epoch"`
+	Offset        uint64 `json:"offset"`
+}
+
+type push struct {
+	Channel      string `json:"channel"`
+	Pub          *Publication `json:"pub,omitempty"`
+	Join         *struct {
+		Info ClientInfo `json:"info"`
+	} `json:"join,omitempty"`
+	Leave        *struct {
+		Info ClientInfo `json:"info"`
+	} `json:"leave,omitempty"`
+	Unsubscribe  *struct {
+		Code    uint32 `json:"code"`
+		Reason  string `json:"reason"`
+	} `json:"unsubscribe,omitempty"`
+	Message      *struct {
+		Data interface{} `json:"data"`
+	} `json:"message,omitempty"`
+	Disconnect   *struct {
+		Code       uint32 `json:"code"`
+		Reason     string `json:"reason"`
+		Reconnect  bool `json:"reconnect"`
+	} `json:"disconnect,omitempty"`
+}
+
+type reply struct {
+	Id      uint32
+	Error   *ReplyError
+	Push    *push
+	Result  json.RawMessage
+}
+
+func (r *reply) UnmarshalJSON(buf []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &fields); err != nil {
+		return err
+	}
+	for k, v := range fields {
+		var err error
+		switch k {
+		case "id":
+			err = json.Unmarshal(v, &r.Id)
+		case "error":
+			err = json.Unmarshal(v, &r.Error)
+		case "push":
+			err = json.Unmarshal(v, &r.Push)
+		default:
+			// result of the command method
+			r.Result = v
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package centrifuge
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+type Subscription struct {
+	*ws.PublicChannel
+	client       *ws.ProtocolClient
+	Channel      string
+	mu           sync.Mutex
+	recoverable  bool
+	epoch        string
+}
+
+// Stream position of the last publication seen.
+func (s *Subscription) Position() StreamPosition {
+	pos, _ := parsePosition(s.client.LastMessageId(s.Channel))
+	return pos
+}
+
+// Resume id of a publication, empty if the channel can't be recovered.
+func (s *Subscription) positionId(offset uint64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.recoverable || s.epoch == "" {
+		return ""
+	}
+	return formatPosition(StreamPosition{Offset: offset, Epoch: s.epoch})
+}
+
+func (s *Subscription) UpdateClientState(connected bool) {
+	if connected {
+		// Client connected, (re)subscribe and recover missed publications.
+		s.Subscribe()
+	} else {
+		s.SetActive(false)
+	}
+}
+
+func (s *Subscription) handleSubscribeReply(r *reply) error {
+	if r == nil {
+		// disconnected, resubscribed on reconnect.
+		return nil
+	}
+	if r.Error != nil {
+		log.Println("Centrifuge subscribe failed:", s.Channel, r.Error)
+		s.client.Dispatch(&Event{
+			Channel: s.Channel,
+			Event: "error",
+			Data: r.Error,
+		})
+		return nil
+	}
+	var res subscribeResult
+	if err := json.Unmarshal(r.Result, &res); err != nil {
+		log.Println("Bad subscribe result:", s.Channel, err)
+		return nil
+	}
+	s.mu.Lock()
+	s.recoverable = res.Recoverable
+	s.epoch = res.Epoch
+	s.mu.Unlock()
+	if !res.Recovered {
+		// history was lost, handlers get a gap event.
+		s.client.ResumeFailed(s.Channel)
+	}
+	s.client.Subscribed(s.Channel)
+	s.client.Dispatch(&Event{
+		Channel: s.Channel,
+		Event: "subscribed",
+		Data: map[string]interface{}{
+			"recoverable": res.Recoverable,
+			"recovered": res.Recovered,
+		},
+	})
+	// deliver publications missed while disconnected.
+	for _, pub := range res.Publications {
+		s.client.Dispatch(publicationEvent(s.Channel, pub))
+		s.client.Seen(s.Channel, s.positionId(pub.Offset))
+	}
+	s.client.Seen(s.Channel, s.positionId(res.Offset))
+	return nil
+}
+
+func newSubscription(channel string, proto *centrifugeProtocol) *Subscription {
+	return &Subscription{
+		PublicChannel: ws.NewPublicChannel(channel, proto.client),
+		client: proto.client,
+		Channel: channel,
+	}
+}
+
+func NewSubscription(channel string, client *CentrifugeClient) *Subscription {
+	return newSubscription(channel, client.proto)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+  ws "github.com/Neopallium/websocket-client-go/websocket"
+  "github.com/Neopallium/websocket-client-go/pusher"
+  "encoding/json"
+  "flag"
+  "fmt"
+  "os"
+  "os/signal"
+  "sort"
+  "strings"
+  "sync"
+  "syscall"
+  "time"
+)
+
+// Latency samples of one measurement.
+type latencies struct {
+  sync.Mutex
+  name    string
+  samples []time.Duration
+}
+
+func (l *latencies) add(d time.Duration) {
+  l.Lock()
+  defer l.Unlock()
+  l.samples = append(l.samples, d)
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+  i := int(float64(len(sorted) - 1) * p / 100)
+  return sorted[i]
+}
+
+func (l *latencies) print() {
+  l.Lock()
+  defer l.Unlock()
+  if len(l.samples) == 0 {
+    fmt.Printf("%-14s no samples\n", l.name)
+    return
+  }
+  sorted := append([]time.Duration(nil), l.samples...)
+  sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+  fmt.Printf("%-14s n=%-7d min=%-10v p50=%-10v p90=%-10v p99=%-10v max=%v\n", l.name, len(sorted),
+    sorted[0], percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99), sorted[len(sorted) - 1])
+}
+
+// Payload of the benchmark messages, ts is the send time in unix nanoseconds.
+type benchMessage struct {
+  Ts int64 `json:"ts"`
+}
+
+type bench struct {
+  connect    latencies
+  subscribe  latencies
+  fanout     latencies
+}
+
+// Measure the latencies of one client.
+type benchClient struct {
+  b           *bench
+  mu          sync.Mutex
+  start       time.Time
+  connectedAt time.Time
+}
+
+// Connect latency is measured from the last dial, also after reconnects.
+func (c *benchClient) stateChange(st ws.ConnState) {
+  if st == ws.StateConnecting {
+    c.mu.Lock()
+    c.start = time.Now()
+    c.mu.Unlock()
+  }
+}
+
+func (c *benchClient) HandleEvent(e ws.Event) {
+  now := time.Now()
+  switch e.GetEvent() {
+  case "pusher:connection_established":
+    c.mu.Lock()
+    c.connectedAt = now
+    c.b.connect.add(now.Sub(c.start))
+    c.mu.Unlock()
+  case "pusher_internal:subscription_succeeded":
+    // subscribe is sent once connected.
+    c.mu.Lock()
+    c.b.subscribe.add(now.Sub(c.connectedAt))
+    c.mu.Unlock()
+  default:
+    var msg benchMessage
+    if err := json.Unmarshal([]byte(e.GetDataString()), &msg); err == nil && msg.Ts > 0 {
+      c.b.fanout.add(now.Sub(time.Unix(0, msg.Ts)))
+    }
+  }
+}
+
+// Send client events with the send time to all channels.
+func publishBench(client *pusher.PusherClient, channels []string, event string, interval time.Duration, stop chan bool) {
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-ticker.C:
+      for _, channel := range channels {
+        buf, _ := json.Marshal(benchMessage{Ts: time.Now().UnixNano()})
+        if err := client.Trigger(channel, event, string(buf)); err != nil {
+          fmt.Fprintln(os.Stderr, "Publish failed:", err)
+          return
+        }
+      }
+    case <-stop:
+      return
+    }
+  }
+}
+
+func runBench(args []string) {
+  flags := flag.NewFlagSet("bench", flag.ExitOnError)
+  var url, key, secret, prefix, event string
+  var clients, channels int
+  var ramp, duration, interval time.Duration
+  flags.StringVar(&url, "url", "", "Pusher url.")
+
+  flags.StringVar(&key, "key", "", "Pusher app key.")
+
+  flags.IntVar(&clients, "clients", 100, "Number of connections.")
+
+  flags.IntVar(&channels, "channels", 1, "Channels per connection.")
+
+  flags.StringVar(&prefix, "prefix", "bench-", "Channel name prefix, use 'private-' with -secret to publish client events.")
+
+  flags.DurationVar(&ramp, "ramp", time.Second * 10, "Time to open all connections.")
+
+  flags.DurationVar(&duration, "duration", time.Second * 30, "Time to measure after the ramp up.")
+
+  flags.StringVar(&secret, "secret", "", "App secret, signs private channels and publishes client events to measure fan-out.")
+
+  flags.StringVar(&event, "event", "client-bench", "Event name of the published messages.")
+
+  flags.DurationVar(&interval, "interval", time.Second, "Publish interval.")
+
+  flags.Parse(args)
+
+  if (url == "") == (key == "") {
+    usageError(flags, "Set one of the `url` or `key` flags.")
+  }
+  if clients <= 0 || channels <= 0 {
+    usageError(flags, "Need at least one client and channel.")
+  }
+  if secret != "" && !strings.HasPrefix(prefix, "private-") && !strings.HasPrefix(prefix, "presence-") {
+    usageError(flags, "Client events need a 'private-' or 'presence-' channel `prefix` with -secret.")
+  }
+
+  cf := pusher.DefaultPusher
+  if secret != "" {
+    appKey := key
+    if appKey == "" {
+      appKey = urlAppKey(url)
+    }
+    cf.Authorizer = pusher.SecretAuthorizer(appKey, secret, "bench")
+  }
+  names := make([]string, channels)
+  for i := range names {
+    names[i] = fmt.Sprintf("%s%d", prefix, i)
+  }
+
+  b := &bench{
+    connect: latencies{name: "connect"},
+    subscribe: latencies{name: "subscribe ack"},
+    fanout: latencies{name: "fan-out"},
+  }
+  sig := make(chan os.Signal, 1)
+  signal.Notify(sig, syscall.SIGHUP)
+  signal.Notify(sig, syscall.SIGINT)
+
+  var conns []*pusher.PusherClient
+  defer func() {
+    for _, c := range conns {
+      c.Close()
+    }
+  }()
+  fmt.Fprintf(os.Stderr, "Opening %d connections with %d channels over %v\n", clients, channels, ramp)
+  delay := ramp / time.Duration(clients)
+  for i := 0; i < clients; i++ {
+    var client *pusher.PusherClient
+    bc := &benchClient{b: b, start: time.Now()}
+    ccf := cf
+    // bound before connecting, to not miss pusher:connection_established.
+    ccf.Handler = bc
+    ccf.OnStateChange = bc.stateChange
+    if key != "" {
+      client = ccf.NewPusher(key)
+    } else {
+      c, err := ccf.NewPusherUrl(url)
+      if err != nil {
+        usageError(flags, "Bad url: " + url)
+      }
+      client = c
+    }
+    for _, name := range names {
+      client.Subscribe(name)
+    }
+    conns = append(conns, client)
+    select {
+    case <-time.After(delay):
+    case <-sig:
+      b.print()
+      return
+    }
+  }
+
+  stop := make(chan bool)
+  if secret != "" {
+    // pusher doesn't send client events back to the sender.
+    go publishBench(conns[0], names, event, interval, stop)
+  }
+  fmt.Fprintf(os.Stderr, "Measuring for %v\n", duration)
+  select {
+  case <-time.After(duration):
+  case <-sig:
+  }
+  close(stop)
+  b.print()
+}
+
+func (b *bench) print() {
+  b.connect.print()
+  b.subscribe.print()
+  b.fanout.print()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+  ws "github.com/Neopallium/websocket-client-go/websocket"
+  "github.com/Neopallium/websocket-client-go/pusher"
+  "bufio"
+  "encoding/json"
+  "flag"
+  "fmt"
+  "io"
+  "net/url"
+  "os"
+  "path"
+  "strings"
+)
+
+// Match event names against wildcard patterns.  Without patterns all events
+// except pings/pongs match.
+func eventFilter(patterns []string) func(ws.Event) bool {
+  return func(e ws.Event) bool {
+    name := e.GetEvent()
+    if len(patterns) == 0 {
+      return name != "pusher:ping" && name != "pusher:pong"
+    }
+    for _, p := range patterns {
+      if ok, _ := path.Match(p, name); ok {
+        return true
+      }
+    }
+    return false
+  }
+}
+
+// App key from the "/app/<key>" path of a Pusher url.
+func urlAppKey(pusherUrl string) string {
+  u, err := url.Parse(pusherUrl)
+  if err != nil {
+    return ""
+  }
+  if i := strings.Index(u.Path, "/app/"); i >= 0 {
+    return strings.SplitN(u.Path[i + 5:], "/", 2)[0]
+  }
+  return ""
+}
+
+// Send client events read from stdin.  Each line is either
+// `channel event data` or a JSON object with channel, event and data.
+func triggerEvents(client *pusher.PusherClient, r io.Reader) {
+  scanner := bufio.NewScanner(r)
+  for scanner.Scan() {
+    line := strings.TrimSpace(scanner.Text())
+    if line == "" {
+      continue
+    }
+    var e pusher.Event
+    if strings.HasPrefix(line, "{") {
+      if err := json.Unmarshal([]byte(line), &e); err != nil {
+        fmt.Fprintln(os.Stderr, "Bad event:", err)
+        continue
+      }
+    } else {
+      parts := strings.SplitN(line, " ", 3)
+      if len(parts) < 2 {
+        fmt.Fprintln(os.Stderr, "Bad event, expected: channel event [data]")
+        continue
+      }
+      e.Channel = parts[0]
+      e.Event = parts[1]
+      e.Data = "{}"
+      if len(parts) == 3 {
+        e.Data = parts[2]
+      }
+    }
+    if err := client.Trigger(e.Channel, e.Event, e.Data); err != nil {
+      fmt.Fprintln(os.Stderr, err)
+    }
+  }
+}
+
+func runPusher(args []string) {
+  flags := flag.NewFlagSet("pusher", flag.ExitOnError)
+  errorUsage := func(err string) {
+    usageError(flags, err)
+  }
+  var url, key, format, authEndpoint, secret, userId string
+  var channels, events stringList
+  var trigger bool
+  // parse command-line
+  flags.StringVar(&url, "url", "", "Pusher url.")
+
+  flags.StringVar(&key, "key", "", "Pusher app key.")
+
+  flags.Var(&channels, "channel", "Channel to subscribe, can be repeated. (required)")
+
+  flags.Var(&events, "event", "Event to print, supports wildcards (e.g. 'client-*'), can be repeated. (optional)")
+
+  flags.StringVar(&format, "format", FormatText, "Output format: text, json, pretty or ndjson.")
+
+  flags.StringVar(&authEndpoint, "auth-endpoint", "", "Auth endpoint for private/presence channels.")
+
+  flags.StringVar(&secret, "secret", "", "App secret to sign private/presence channels locally.")
+
+  flags.StringVar(&userId, "user-id", "cli", "User id for presence channels signed with -secret.")
+
+  flags.BoolVar(&trigger, "trigger", false, "Send client events read from stdin ('channel event data' per line).")
+
+  flags.Parse(args)
+
+  out, err := newOutput(os.Stdout, format)
+  if err != nil {
+    errorUsage(err.Error())
+  }
+
+  cf := pusher.DefaultPusher
+  cf.OnStateChange = out.state
+
+  switch {
+  case authEndpoint != "" && secret != "":
+    errorUsage("Can't set both `auth-endpoint` and `secret` flags")
+  case authEndpoint != "":
+    cf.Authorizer = pusher.EndpointAuthorizer(authEndpoint, nil)
+  case secret != "":
+    appKey := key
+    if appKey == "" {
+      appKey = urlAppKey(url)
+    }
+    cf.Authorizer = pusher.SecretAuthorizer(appKey, secret, userId)
+  }
+
+  var client *pusher.PusherClient
+
+  switch {
+  case len(channels) == 0:
+     errorUsage("Missing required `channel` flag.")
+  case key != "" && url != "":
+     errorUsage("Can't set both `url` and `key` flags")
+  case key != "":
+    fmt.Fprintf(os.Stderr, "Connect to Pusher app key: %s\n", key)
+    client = cf.NewPusher(key)
+  case url != "":
+    fmt.Fprintf(os.Stderr, "Connect to Pusher url: %s\n", url)
+    c, err := cf.NewPusherUrl(url)
+    if err != nil {
+      errorUsage("Bad url: " + url)
+    }
+    client = c
+  default:
+     errorUsage("Missing `url` or `key` flag")
+  }
+
+  // print events from all channels that match the patterns.
+  client.Use(ws.Filter(eventFilter(events)))
+  client.BindAll(out)
+
+  for _, channel := range channels {
+    fmt.Fprintln(os.Stderr, "Subscribe:", channel)
+    client.Subscribe(channel)
+  }
+
+  if trigger {
+    go triggerEvents(client, os.Stdin)
+  }
+
+  waitForSignal()
+
+  // close client.
+  client.Close()
+  out.close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+  ws "github.com/Neopallium/websocket-client-go/websocket"
+  "github.com/gorilla/websocket"
+  "bufio"
+  "encoding/hex"
+  "flag"
+  "fmt"
+  "net/http"
+  "os"
+  "strings"
+  "sync"
+)
+
+// Prints inbound messages, text as is and binary as a hex dump.
+type framePrinter struct {
+  sync.Mutex
+}
+
+func (p *framePrinter) message(typ int, msg []byte) {
+  p.Lock()
+  defer p.Unlock()
+  switch typ {
+  case websocket.TextMessage:
+    fmt.Println("<", string(msg))
+  case websocket.BinaryMessage:
+    fmt.Printf("< binary %d bytes\n%s", len(msg), hex.Dump(msg))
+  }
+}
+
+func (p *framePrinter) state(st ws.ConnState) {
+  p.Lock()
+  defer p.Unlock()
+  fmt.Fprintln(os.Stderr, "*", st)
+}
+
+func parseHeaders(headers []string) (http.Header, error) {
+  h := http.Header{}
+  for _, line := range headers {
+    parts := strings.SplitN(line, ":", 2)
+    if len(parts) != 2 {
+      return nil, fmt.Errorf("bad header, expected `Name: value`: %s", line)
+    }
+    h.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+  }
+  return h, nil
+}
+
+func runRaw(args []string) {
+  flags := flag.NewFlagSet("raw", flag.ExitOnError)
+  var headers, subprotocols stringList
+  var ping string
+  flags.Var(&headers, "header", "Extra handshake header (`Name: value`), can be repeated.")
+
+  flags.Var(&subprotocols, "subprotocol", "Requested subprotocol, can be repeated.")
+
+  flags.StringVar(&ping, "ping", "", "Text message sent as a keepalive ping, empty for websocket ping frames.")
+
+  flags.Parse(args)
+
+  if flags.NArg() != 1 {
+    usageError(flags, "Expected one websocket url.")
+  }
+  header, err := parseHeaders(headers)
+  if err != nil {
+    usageError(flags, err.Error())
+  }
+
+  printer := &framePrinter{}
+  cf := ws.DefaultConfig
+  cf.Header = header
+  cf.Subprotocols = subprotocols
+  cf.OnMessage = printer.message
+  cf.OnStateChange = printer.state
+  cf.PingMessage = ping
+  cf.PingFrames = ping == ""
+  client, err := cf.NewClient(flags.Arg(0))
+  if err != nil {
+    usageError(flags, "Bad url: " + flags.Arg(0))
+  }
+
+  // send lines from stdin.
+  go func() {
+    scanner := bufio.NewScanner(os.Stdin)
+    for scanner.Scan() {
+      client.SendMessage([]byte(scanner.Text()))
+    }
+  }()
+
+  waitForSignal()
+
+  client.Close()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+  "github.com/Neopallium/websocket-client-go/pusher/pusherd"
+  "flag"
+  "fmt"
+  "log"
+  "net/http"
+  "os"
+  "time"
+)
+
+func runServe(args []string) {
+  flags := flag.NewFlagSet("serve", flag.ExitOnError)
+  var addr string
+  var app pusherd.App
+  var activityTimeout time.Duration
+  flags.StringVar(&addr, "addr", ":8080", "Listen address.")
+
+  flags.StringVar(&app.Id, "app-id", "1", "App id for the HTTP API.")
+
+  flags.StringVar(&app.Key, "key", "key", "App key.")
+
+  flags.StringVar(&app.Secret, "secret", "secret", "App secret.")
+
+  flags.BoolVar(&app.ClientEvents, "client-events", false, "Allow client events.")
+
+  flags.DurationVar(&activityTimeout, "activity-timeout", pusherd.DefaultServer.ActivityTimeout, "Activity timeout sent to clients.")
+
+  flags.Parse(args)
+
+  if flags.NArg() != 0 {
+    usageError(flags, "Unexpected arguments.")
+  }
+
+  cf := pusherd.DefaultServer
+  cf.ActivityTimeout = activityTimeout
+  srv := cf.NewServer(app)
+  go func() {
+    log.Fatal(http.ListenAndServe(addr, srv))
+  }()
+  fmt.Fprintf(os.Stderr, "Listening on %s, clients connect to /app/%s, HTTP API at /apps/%s\n", addr, app.Key, app.Id)
+  waitForSignal()
+  srv.Close()
+}
@@ -0,0 +1,63 @@
+package graphqlws
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// graphql-transport-ws message
+type Event struct {
+	Id       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Payload  interface{} `json:"payload,omitempty"`
+}
+
+func (e *Event) GetEvent() string {
+	return e.Type
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Type = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Id
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Id = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Payload
+}
+
+func (e *Event) SetData(data interface{}) {
+	e.Payload = data
+}
+
+func (e *Event) GetDataString() string {
+	// Normalize Payload as a string value.
+	switch e.Payload.(type) {
+	case string:
+		return e.Payload.(string)
+	default:
+		buf, err := json.Marshal(e.Payload)
+		if err != nil {
+			log.Fatal("JSON Marshaller failed:", err)
+		}
+		return string(buf)
+	}
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Payload = data
+}
+
+// Payload of a "subscribe" message.
+type Request struct {
+	Query          string `json:"query"`
+	Variables      map[string]interface{} `json:"variables,omitempty"`
+	OperationName  string `json:"operationName,omitempty"`
+	Extensions     map[string]interface{} `json:"extensions,omitempty"`
+}
@@ -0,0 +1,103 @@
+package graphqlws
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type GraphQLClient struct {
+	*ws.ProtocolClient
+	mu           sync.Mutex
+	nextId       uint64
+	// running operations, keyed by request.
+	requests     map[string]*Subscription
+}
+
+func (c *GraphQLClient) makeId() string {
+	c.nextId++
+	return strconv.FormatUint(c.nextId, 10)
+}
+
+func requestKey(req *Request) string {
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return req.Query
+	}
+	return string(buf)
+}
+
+// Start a subscription operation.  Results are delivered to the handlers bound
+// to the "next", "error" and "complete" events.  The same request returns the
+// running operation.
+func (c *GraphQLClient) SubscribeRequest(req *Request) *Subscription {
+	key := requestKey(req)
+	c.mu.Lock()
+	sub := c.requests[key]
+	if sub == nil {
+		sub = NewSubscription(c.makeId(), req, c)
+		c.requests[key] = sub
+	}
+	c.mu.Unlock()
+	if c.FindChannel(sub.Id) == nil {
+		c.AddChannel(sub.Id, sub)
+	}
+	return sub
+}
+
+// Start a subscription for a query document.  Use the Subscription's Id to
+// unsubscribe.
+func (c *GraphQLClient) Subscribe(query string) ws.Channel {
+	return c.SubscribeRequest(&Request{Query: query})
+}
+
+// Stop the subscription with the id.
+func (c *GraphQLClient) Unsubscribe(id string) {
+	c.mu.Lock()
+	for key, sub := range c.requests {
+		if sub.Id == id {
+			delete(c.requests, key)
+		}
+	}
+	c.mu.Unlock()
+	c.ProtocolClient.Unsubscribe(id)
+}
+
+type GraphQLConfig struct {
+	ws.Config
+	// payload of the "connection_init" message.
+	InitPayload       interface{}
+}
+
+var (
+	DefaultGraphQL = GraphQLConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 120,
+			PingTimeout:     time.Second * 30,
+			Subprotocols:    []string{"graphql-transport-ws"},
+		},
+	}
+)
+
+func (cf GraphQLConfig) NewGraphQLUrl(graphqlUrl string) (*GraphQLClient, error) {
+	u, err := url.Parse(graphqlUrl)
+	if err != nil {
+		return nil, err
+	}
+	proto := &graphqlProtocol{
+		initPayload: cf.InitPayload,
+	}
+	return &GraphQLClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		requests: make(map[string]*Subscription),
+	}, nil
+}
+
+func NewGraphQLUrl(graphqlUrl string) (*GraphQLClient, error) {
+	return DefaultGraphQL.NewGraphQLUrl(graphqlUrl)
+}
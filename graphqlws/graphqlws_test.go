@@ -0,0 +1,81 @@
+package graphqlws
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler, "graphql-transport-ws")
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) *Event {
+	e := &Event{}
+	if err := conn.ReadJSON(e); err != nil {
+		t.Error(err)
+		return nil
+	}
+	return e
+}
+
+func TestSubscribe(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		if e := readEvent(t, conn); e == nil || e.Type != "connection_init" {
+			t.Errorf("expected connection_init, got %+v", e)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte("{bad json"))
+		conn.WriteJSON(&Event{Type: "connection_ack"})
+		sub := readEvent(t, conn)
+		if sub == nil || sub.Type != "subscribe" {
+			t.Errorf("expected subscribe, got %+v", sub)
+			return
+		}
+		conn.WriteJSON(&Event{Id: sub.Id, Type: "next", Payload: map[string]interface{}{"data": 1}})
+		conn.WriteJSON(&Event{Id: sub.Id, Type: "complete"})
+		// a new operation for the same query once the old one is done.
+		if e := readEvent(t, conn); e == nil || e.Type != "subscribe" || e.Id == sub.Id {
+			t.Errorf("expected a new subscribe, got %+v", e)
+		}
+		<-done
+	})
+	defer close(done)
+	client, err := NewGraphQLUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 2)
+	sub := client.Subscribe("subscription { tick }")
+	if again := client.Subscribe("subscription { tick }"); again != sub {
+		t.Fatal("same query started a second operation")
+	}
+	sub.BindAllFunc(func(e ws.Event) {
+		got <- e
+	})
+	for _, expected := range []string{"next", "complete"} {
+		select {
+		case e := <-got:
+			if e.GetEvent() != expected {
+				t.Fatalf("expected %s, got %s", expected, e.GetEvent())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for", expected)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for client.FindChannel(sub.(*Subscription).Id) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("finished subscription wasn't removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.Subscribe("subscription { tick }") == sub {
+		t.Fatal("finished operation was reused")
+	}
+}
@@ -0,0 +1,91 @@
+package graphqlws
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+)
+
+// Codec for the graphql-transport-ws protocol.
+type graphqlProtocol struct {
+	client       *ws.ProtocolClient
+	initPayload  interface{}
+}
+
+func (p *graphqlProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *graphqlProtocol) HandshakeFrame() []byte {
+	buf, _ := p.EncodeEvent(&Event{
+		Type: "connection_init",
+		Payload: p.initPayload,
+	})
+	return buf
+}
+
+func (p *graphqlProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (p *graphqlProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	event := &Event{}
+	if err := json.Unmarshal(msg, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (p *graphqlProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	switch e.GetEvent() {
+	case "ping":
+		return ws.KindPing, nil
+	case "pong":
+		return ws.KindPong, nil
+	case "connection_ack":
+		return ws.KindConnected, nil
+	case "next":
+		return ws.KindSubscribed, nil
+	case "error", "complete":
+		return ws.KindUnsubscribed, nil
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *graphqlProtocol) subscription(id string) *Subscription {
+	sub, _ := p.client.FindChannel(id).(*Subscription)
+	return sub
+}
+
+func (p *graphqlProtocol) SubscribeFrame(id string) []byte {
+	sub := p.subscription(id)
+	if sub == nil {
+		return nil
+	}
+	buf, _ := p.EncodeEvent(&Event{
+		Id: id,
+		Type: "subscribe",
+		Payload: sub.Request,
+	})
+	return buf
+}
+
+func (p *graphqlProtocol) UnsubscribeFrame(id string) []byte {
+	// the server already finished the operation.
+	if sub := p.subscription(id); sub == nil || !sub.finish() {
+		return nil
+	}
+	buf, _ := p.EncodeEvent(&Event{
+		Id: id,
+		Type: "complete",
+	})
+	return buf
+}
+
+func (p *graphqlProtocol) PingFrame() []byte {
+	return []byte(`{"type":"ping"}`)
+}
+
+func (p *graphqlProtocol) PongFrame() []byte {
+	return []byte(`{"type":"pong"}`)
+}
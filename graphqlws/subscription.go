@@ -0,0 +1,44 @@
+package graphqlws
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"sync"
+)
+
+type Subscription struct {
+	*ws.PublicChannel
+	client     *GraphQLClient
+	Id         string
+	Request    *Request
+	mu         sync.Mutex
+	done       bool
+}
+
+func (s *Subscription) HandleEvent(event ws.Event) {
+	s.PublicChannel.HandleEvent(event)
+	switch event.GetEvent() {
+	case "error", "complete":
+		// operation finished, don't resubscribe.
+		s.finish()
+		s.client.Unsubscribe(s.Id)
+	}
+}
+
+// Mark the operation as finished, returns false if it already was.
+func (s *Subscription) finish() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := s.done
+	s.done = true
+	return !done
+}
+
+func NewSubscription(id string, req *Request, client *GraphQLClient) *Subscription {
+	return &Subscription{
+		PublicChannel: ws.NewPublicChannel(id, client.ProtocolClient),
+		client: client,
+		Id: id,
+		Request: req,
+	}
+}
@@ -0,0 +1,42 @@
+// Package wstest has the websocket server fixture shared by the package tests.
+package wstest
+
+import (
+	"github.com/gorilla/websocket"
+
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Upgrade requests to websockets for handler, offering the subprotocols.  The
+// connection is closed when handler returns.
+func Handler(t testing.TB, handler func(conn *websocket.Conn, r *http.Request), subprotocols ...string) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		Subprotocols: subprotocols,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		handler(conn, r)
+	}
+}
+
+// Start a server for h, closed with the test.  Returns the server's ws:// url.
+func Serve(t testing.TB, h http.Handler) string {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// Start a websocket server, closed with the test.  Returns its ws:// url.
+func Server(t testing.TB, handler func(conn *websocket.Conn), subprotocols ...string) string {
+	return Serve(t, Handler(t, func(conn *websocket.Conn, r *http.Request) {
+		handler(conn)
+	}, subprotocols...))
+}
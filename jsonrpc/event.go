@@ -0,0 +1,57 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Notification from the server.  Subscription notifications are routed to the
+// subscription's channel.
+type Event struct {
+	Channel  string
+	Method   string
+	Params   interface{}
+	msg      *message  // response, not sent to handlers
+}
+
+func (e *Event) GetEvent() string {
+	return e.Method
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Method = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Channel
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Params
+}
+
+func (e *Event) SetData(data interface{}) {
+	e.Params = data
+}
+
+func (e *Event) GetDataString() string {
+	// Normalize Params as a string value.
+	switch e.Params.(type) {
+	case string:
+		return e.Params.(string)
+	default:
+		buf, err := json.Marshal(e.Params)
+		if err != nil {
+			log.Fatal("JSON Marshaller failed:", err)
+		}
+		return string(buf)
+	}
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Params = data
+}
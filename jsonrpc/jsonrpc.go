@@ -0,0 +1,173 @@
+package jsonrpc
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrDisconnected = errors.New("jsonrpc: disconnected before response")
+)
+
+type RPCClient struct {
+	*ws.ProtocolClient
+	cf     RPCConfig
+	proto  *rpcProtocol
+}
+
+func (c *RPCClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.cf.CallTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.cf.CallTimeout)
+}
+
+// Call a method and wait for the result.  CallTimeout is used if ctx doesn't
+// have a deadline.
+func (c *RPCClient) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	done := make(chan *message, 1)
+	id := c.proto.send(method, params, func(r *message) {
+		done <- r
+	})
+	select {
+	case r := <-done:
+		if r == nil {
+			return nil, ErrDisconnected
+		}
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		return r.Result, nil
+	case <-ctx.Done():
+		c.proto.forget(id)
+		return nil, ctx.Err()
+	}
+}
+
+// Call a method and decode the result into v.
+func (c *RPCClient) CallResult(ctx context.Context, v interface{}, method string, params interface{}) error {
+	res, err := c.Call(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(res, v)
+}
+
+// Send a notification, no response is expected.
+func (c *RPCClient) Notify(method string, params interface{}) {
+	c.SendEvent(&Event{
+		Method: method,
+		Params: params,
+	})
+}
+
+// Send a batch of requests and wait for all responses.  Responses are in the
+// same order as the requests.
+func (c *RPCClient) Batch(ctx context.Context, reqs []*Request) ([]*Response, error) {
+	if len(reqs) == 0 {
+		// an empty batch is an invalid request.
+		return nil, nil
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	type result struct {
+		idx  int
+		msg  *message
+	}
+	done := make(chan result, len(reqs))
+	batch := make([]*message, len(reqs))
+	ids := make([]uint64, len(reqs))
+	for i, req := range reqs {
+		idx := i
+		ids[i] = c.proto.register(func(r *message) {
+			done <- result{idx, r}
+		})
+		batch[i] = newRequest(ids[i], req.Method, req.Params)
+	}
+	c.proto.sendMessage(batch)
+	resps := make([]*Response, len(reqs))
+	for n := 0; n < len(reqs); n++ {
+		select {
+		case r := <-done:
+			if r.msg == nil {
+				return nil, ErrDisconnected
+			}
+			resps[r.idx] = &Response{Result: r.msg.Result, Error: r.msg.Error}
+		case <-ctx.Done():
+			for _, id := range ids {
+				c.proto.forget(id)
+			}
+			return nil, ctx.Err()
+		}
+	}
+	return resps, nil
+}
+
+// Subscribe with the SubscribeMethod and params, the subscription is named
+// for use with Unsubscribe.
+func (c *RPCClient) SubscribeParams(name string, params ...interface{}) *Subscription {
+	if sub, ok := c.FindChannel(name).(*Subscription); ok {
+		return sub
+	}
+	// create a new subscription.
+	sub := NewSubscription(name, params, c)
+	c.AddChannel(name, sub)
+	return sub
+}
+
+// Subscribe to a subscription type (e.g. "newHeads").
+func (c *RPCClient) Subscribe(name string) ws.Channel {
+	return c.SubscribeParams(name, name)
+}
+
+type RPCConfig struct {
+	ws.Config
+	CallTimeout         time.Duration
+	// Cheap method used as a heartbeat, no heartbeats if empty.
+	PingMethod          string
+	SubscribeMethod     string
+	UnsubscribeMethod   string
+	NotificationMethod  string
+}
+
+var (
+	DefaultRPC = RPCConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 120,
+			PingTimeout:     time.Second * 30,
+		},
+		CallTimeout:        time.Second * 30,
+		SubscribeMethod:    "eth_subscribe",
+		UnsubscribeMethod:  "eth_unsubscribe",
+		NotificationMethod: "eth_subscription",
+	}
+)
+
+func (cf RPCConfig) NewRPCUrl(rpcUrl string) (*RPCClient, error) {
+	u, err := url.Parse(rpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	proto := &rpcProtocol{
+		cf: cf,
+		pending: make(map[uint64]responseFn),
+		subs: make(map[string]string),
+	}
+	return &RPCClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		cf: cf,
+		proto: proto,
+	}, nil
+}
+
+func NewRPCUrl(rpcUrl string) (*RPCClient, error) {
+	return DefaultRPC.NewRPCUrl(rpcUrl)
+}
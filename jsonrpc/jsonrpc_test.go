@@ -0,0 +1,131 @@
+package jsonrpc
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler)
+}
+
+func readRequest(t *testing.T, conn *websocket.Conn) *message {
+	m := &message{}
+	if err := conn.ReadJSON(m); err != nil {
+		t.Error(err)
+		return nil
+	}
+	return m
+}
+
+func TestCallAndSubscribe(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		for i := 0; i < 2; i++ {
+			req := readRequest(t, conn)
+			if req == nil {
+				return
+			}
+			switch req.Method {
+			case "eth_subscribe":
+				conn.WriteMessage(websocket.TextMessage, []byte(`not json`))
+				conn.WriteJSON(&message{Jsonrpc: "2.0", Id: req.Id, Result: json.RawMessage(`"0x1"`)})
+				conn.WriteJSON(&message{
+					Jsonrpc: "2.0",
+					Method: "eth_subscription",
+					Params: map[string]interface{}{"subscription": "0x1", "result": "head"},
+				})
+			case "eth_blockNumber":
+				conn.WriteJSON(&message{Jsonrpc: "2.0", Id: req.Id, Result: json.RawMessage(`"0x10"`)})
+			default:
+				t.Errorf("unexpected request: %+v", req)
+			}
+		}
+		<-done
+	})
+	defer close(done)
+	client, err := NewRPCUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 1)
+	client.Subscribe("newHeads").BindAllFunc(func(e ws.Event) {
+		got <- e
+	})
+	select {
+	case e := <-got:
+		if e.GetChannel() != "newHeads" || e.GetData() != "head" {
+			t.Fatalf("bad notification: %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for notification")
+	}
+	var block string
+	if err := client.CallResult(context.Background(), &block, "eth_blockNumber", nil); err != nil {
+		t.Fatal(err)
+	}
+	if block != "0x10" {
+		t.Fatalf("expected 0x10, got %s", block)
+	}
+}
+
+func TestEmptyBatch(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		<-done
+	})
+	defer close(done)
+	client, err := NewRPCUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resps, err := client.Batch(ctx, nil)
+	if err != nil || len(resps) != 0 {
+		t.Fatalf("expected empty result, got %v %v", resps, err)
+	}
+}
+
+func TestPingFrames(t *testing.T) {
+	done := make(chan struct{})
+	pinged := make(chan struct{}, 1)
+	u := testServer(t, func(conn *websocket.Conn) {
+		conn.SetPingHandler(func(string) error {
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+			return conn.WriteMessage(websocket.PongMessage, nil)
+		})
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		<-done
+	})
+	defer close(done)
+	cf := DefaultRPC
+	cf.ActivityTimeout = 100 * time.Millisecond
+	client, err := cf.NewRPCUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	select {
+	case <-pinged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for ping frame")
+	}
+}
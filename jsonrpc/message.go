@@ -0,0 +1,59 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Error struct {
+	Code     int `json:"code"`
+	Message  string `json:"message"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: error %d: %s", e.Code, e.Message)
+}
+
+// Request in a batch.
+type Request struct {
+	Method  string
+	Params  interface{}
+}
+
+// Response to a request in a batch.
+type Response struct {
+	Result  json.RawMessage
+	Error   *Error
+}
+
+// Request, response or notification.
+type message struct {
+	Jsonrpc  string `json:"jsonrpc"`
+	Id       json.RawMessage `json:"id,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Params   interface{} `json:"params,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    *Error `json:"error,omitempty"`
+}
+
+func (m *message) id() (uint64, bool) {
+	var id uint64
+	if len(m.Id) == 0 || json.Unmarshal(m.Id, &id) != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+func (m *message) isNotification() bool {
+	return m.Method != "" && len(m.Id) == 0
+}
+
+func newRequest(id uint64, method string, params interface{}) *message {
+	return &message{
+		Jsonrpc: "2.0",
+		Id: json.RawMessage(fmt.Sprint(id)),
+		Method: method,
+		Params: params,
+	}
+}
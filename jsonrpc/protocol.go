@@ -0,0 +1,261 @@
+package jsonrpc
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"bytes"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+type responseFn func(*message)
+
+// Codec for JSON-RPC 2.0, subscriptions use the configured methods.
+type rpcProtocol struct {
+	client     *ws.ProtocolClient
+	cf         RPCConfig
+	mu         sync.Mutex
+	nextId     uint64
+	pending    map[uint64]responseFn
+	subs       map[string]string  // server subscription id -> subscription name
+}
+
+func (p *rpcProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *rpcProtocol) register(fn responseFn) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextId++
+	if fn != nil {
+		p.pending[p.nextId] = fn
+	}
+	return p.nextId
+}
+
+func (p *rpcProtocol) forget(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+func (p *rpcProtocol) sendMessage(v interface{}) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		log.Println("Error sending request:", err)
+		return
+	}
+	p.client.SendMessage(buf)
+}
+
+func (p *rpcProtocol) request(method string, params interface{}, fn responseFn) (uint64, []byte) {
+	id := p.register(fn)
+	buf, err := json.Marshal(newRequest(id, method, params))
+	if err != nil {
+		log.Println("Error encoding request:", method, err)
+		p.forget(id)
+		return id, nil
+	}
+	return id, buf
+}
+
+func (p *rpcProtocol) send(method string, params interface{}, fn responseFn) uint64 {
+	id, buf := p.request(method, params, fn)
+	if buf != nil {
+		p.client.SendMessage(buf)
+	}
+	return id
+}
+
+func (p *rpcProtocol) mapSubscription(old string, id string, name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if old != "" {
+		delete(p.subs, old)
+	}
+	if id != "" {
+		p.subs[id] = name
+	}
+}
+
+func (p *rpcProtocol) Opened() bool {
+	// no handshake, start heartbeats and subscriptions.
+	return true
+}
+
+func (p *rpcProtocol) Closed() {
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = make(map[uint64]responseFn)
+	p.mu.Unlock()
+	// fail all requests waiting for a response.
+	for _, fn := range pending {
+		p.client.SafeCall("", "response", func() {
+			fn(nil)
+		})
+	}
+}
+
+func (p *rpcProtocol) HandshakeFrame() []byte {
+	return nil
+}
+
+// Events are sent as notifications.
+func (p *rpcProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	return json.Marshal(&message{
+		Jsonrpc: "2.0",
+		Method: e.GetEvent(),
+		Params: e.GetData(),
+	})
+}
+
+func (p *rpcProtocol) event(m *message) *Event {
+	if !m.isNotification() {
+		// response, handled by Classify.
+		return &Event{msg: m}
+	}
+	event := &Event{
+		Method: m.Method,
+		Params: m.Params,
+	}
+	if m.Method == p.cf.NotificationMethod {
+		// route subscription notifications to the subscription channel.
+		params, _ := m.Params.(map[string]interface{})
+		id, _ := params["subscription"].(string)
+		p.mu.Lock()
+		event.Channel = p.subs[id]
+		p.mu.Unlock()
+		if event.Channel != "" {
+			event.Params = params["result"]
+		}
+	}
+	return event
+}
+
+func (p *rpcProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	m := &message{}
+	if err := json.Unmarshal(msg, m); err != nil {
+		return nil, err
+	}
+	return p.event(m), nil
+}
+
+func (p *rpcProtocol) DecodeEvents(msg []byte) ([]ws.Event, error) {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 || msg[0] != '[' {
+		e, err := p.DecodeEvent(msg)
+		if err != nil {
+			return nil, err
+		}
+		return []ws.Event{e}, nil
+	}
+	// batch response
+	var batch []*message
+	if err := json.Unmarshal(msg, &batch); err != nil {
+		return nil, err
+	}
+	events := make([]ws.Event, 0, len(batch))
+	for _, m := range batch {
+		events = append(events, p.event(m))
+	}
+	return events, nil
+}
+
+func (p *rpcProtocol) handleResponse(m *message) {
+	if m.Method != "" {
+		log.Println("JSON-RPC server request not supported:", m.Method)
+		return
+	}
+	id, ok := m.id()
+	if !ok {
+		log.Println("JSON-RPC response without id:", m.Error)
+		return
+	}
+	p.mu.Lock()
+	fn := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+	if fn != nil {
+		p.client.SafeCall("", "response", func() {
+			fn(m)
+		})
+	}
+}
+
+func (p *rpcProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	if e, ok := e.(*Event); ok && e.msg != nil {
+		p.handleResponse(e.msg)
+		return ws.KindInternal, nil
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *rpcProtocol) subscription(name string) *Subscription {
+	sub, _ := p.client.FindChannel(name).(*Subscription)
+	return sub
+}
+
+func (p *rpcProtocol) SubscribeFrame(name string) []byte {
+	sub := p.subscription(name)
+	if sub == nil {
+		return nil
+	}
+	_, buf := p.request(p.cf.SubscribeMethod, sub.params, func(r *message) {
+		if r == nil {
+			return
+		}
+		if r.Error != nil {
+			log.Println("JSON-RPC subscribe failed:", name, r.Error)
+			return
+		}
+		var id string
+		if err := json.Unmarshal(r.Result, &id); err != nil {
+			log.Println("JSON-RPC bad subscription id:", name, err)
+			return
+		}
+		sub.setId(id)
+		p.client.Subscribed(name)
+	})
+	return buf
+}
+
+func (p *rpcProtocol) UnsubscribeFrame(name string) []byte {
+	sub := p.subscription(name)
+	if sub == nil {
+		return nil
+	}
+	id := sub.Id()
+	if id == "" {
+		return nil
+	}
+	sub.setId("")
+	_, buf := p.request(p.cf.UnsubscribeMethod, []interface{}{id}, nil)
+	return buf
+}
+
+func (p *rpcProtocol) SendPing() {
+	if p.cf.PingMethod == "" {
+		// fallback to websocket ping frames.
+		p.client.Socket().SendPingFrame()
+		return
+	}
+	p.send(p.cf.PingMethod, nil, func(r *message) {
+		if r != nil {
+			p.client.Socket().HandlePong()
+		}
+	})
+}
+
+func (p *rpcProtocol) PingFrame() []byte {
+	return nil
+}
+
+func (p *rpcProtocol) PongFrame() []byte {
+	return nil
+}
+
+func (p *rpcProtocol) NewChannel(name string) ws.Channel {
+	return newSubscription(name, []interface{}{name}, p)
+}
@@ -0,0 +1,54 @@
+package jsonrpc
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"sync"
+)
+
+type Subscription struct {
+	*ws.PublicChannel
+	proto    *rpcProtocol
+	Name     string
+	params   []interface{}
+	mu       sync.Mutex
+	id       string
+}
+
+// Server subscription id, changes after a reconnect.
+func (s *Subscription) Id() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+func (s *Subscription) UpdateClientState(connected bool) {
+	if connected {
+		// Client connected, (re)subscribe.
+		s.Subscribe()
+	} else {
+		s.setId("")
+		s.SetActive(false)
+	}
+}
+
+func (s *Subscription) setId(id string) {
+	s.mu.Lock()
+	old := s.id
+	s.id = id
+	s.mu.Unlock()
+	s.proto.mapSubscription(old, id, s.Name)
+}
+
+func newSubscription(name string, params []interface{}, proto *rpcProtocol) *Subscription {
+	return &Subscription{
+		PublicChannel: ws.NewPublicChannel(name, proto.client),
+		proto: proto,
+		Name: name,
+		params: params,
+	}
+}
+
+func NewSubscription(name string, params []interface{}, client *RPCClient) *Subscription {
+	return newSubscription(name, params, client.proto)
+}
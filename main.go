@@ -1,86 +1,70 @@
 package main
 
 import (
-  ws "github.com/Neopallium/websocket-client-go/websocket"
-  "github.com/Neopallium/websocket-client-go/pusher"
   "flag"
   "fmt"
   "os"
   "os/signal"
+  "strings"
   "syscall"
 )
 
-func errorUsage(err string) {
+func usageError(flags *flag.FlagSet, err string) {
   fmt.Fprintln(os.Stderr, err)
-  flag.Usage()
+  flags.Usage()
   os.Exit(1)
 }
 
-// Example Handler object
-type chanHandler struct {}
+// Flag that can be repeated.
+type stringList []string
 
-func (o *chanHandler) HandleEvent(event ws.Event) {
-  fmt.Println("Handler: Got Event:", event.GetChannel(), event.GetEvent(), event.GetData())
+func (l *stringList) String() string {
+  return strings.Join(*l, ",")
 }
 
-// Example Handler function
-func eventHandler(event ws.Event) {
-  fmt.Println("HandlerFunc: Got Event:", event.GetChannel(), event.GetEvent(), event.GetData())
+func (l *stringList) Set(v string) error {
+  *l = append(*l, v)
+  return nil
 }
 
-func main() {
-  var url, key, channel, event string
-  // parse command-line
-  flag.StringVar(&url, "url", "", "Pusher url.")
-
-  flag.StringVar(&key, "key", "", "Pusher app key.")
-
-  flag.StringVar(&channel, "channel", "", "Channel subject. (required)")
-
-  flag.StringVar(&event, "event", "", "Channel event to bind to. (optional)")
-
-  flag.Parse()
-
-  var client ws.ChannelClient
-
-  switch {
-  case channel == "":
-     errorUsage("Missing required `channel` flag.")
-  case key != "" && url != "":
-     errorUsage("Can't set both `url` and `key` flags")
-  case key != "":
-    fmt.Printf("Connect to Pusher app key: %s\n", key)
-    client = pusher.NewPusher(key)
-  case url != "":
-    fmt.Printf("Connect to Pusher url: %s\n", url)
-    c, err := pusher.NewPusherUrl(url)
-    if err != nil {
-      errorUsage("Bad url: " + url)
-    }
-    client = c
-  default:
-     errorUsage("Can't set both `url` and `key` flags")
-  }
-
-  fmt.Println("Subscribe:", channel)
-  ch := client.Subscribe(channel)
-
-  fmt.Println("Bind:", event)
-  ch.Bind(event, &chanHandler{})
-  //ch.BindFunc(event, eventHandler)
-
-  // bind to all channel events
-  //client.BindAll(&chanHandler{})
-  //client.BindAllFunc(eventHandler)
-
-  fmt.Println("Wait for exit.")
-  // block until signal
+// block until signal
+func waitForSignal() {
   s := make(chan os.Signal, 1)
   signal.Notify(s, syscall.SIGHUP)
   signal.Notify(s, syscall.SIGINT)
   <- s
+}
 
-  // close client.
-  client.Close()
+func usage() {
+  fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\n", os.Args[0])
+  fmt.Fprintln(os.Stderr, "Commands:")
+  fmt.Fprintln(os.Stderr, "  pusher  Subscribe to Pusher channels (default)")
+  fmt.Fprintln(os.Stderr, "  raw     Raw websocket session, send lines from stdin")
+  fmt.Fprintln(os.Stderr, "  bench   Load test a Pusher server with many connections")
+  fmt.Fprintln(os.Stderr, "  serve   Run a Pusher compatible server")
+  fmt.Fprintln(os.Stderr, "\nUse `<command> -h` for the command's flags.")
 }
 
+func main() {
+  args := os.Args[1:]
+  cmd := "pusher"
+  if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+    cmd, args = args[0], args[1:]
+  }
+  switch cmd {
+  case "pusher":
+    runPusher(args)
+  case "raw":
+    runRaw(args)
+  case "bench":
+    runBench(args)
+  case "serve":
+    runServe(args)
+  case "help":
+    usage()
+  default:
+    fmt.Fprintln(os.Stderr, "Unknown command:", cmd)
+    usage()
+    os.Exit(1)
+  }
+}
@@ -0,0 +1,56 @@
+package mqtt
+
+import (
+	"fmt"
+)
+
+// Received PUBLISH message.  Channel is the subscription filter the topic
+// matched.
+type Event struct {
+	Channel  string
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	Retain   bool
+	Dup      bool
+	pkt      *packet  // control packet, not sent to handlers
+}
+
+func (e *Event) GetEvent() string {
+	return e.Topic
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Topic = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Channel
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Payload
+}
+
+func (e *Event) SetData(data interface{}) {
+	switch data := data.(type) {
+	case []byte:
+		e.Payload = data
+	case string:
+		e.Payload = []byte(data)
+	default:
+		e.Payload = []byte(fmt.Sprint(data))
+	}
+}
+
+func (e *Event) GetDataString() string {
+	return string(e.Payload)
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Payload = []byte(data)
+}
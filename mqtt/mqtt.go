@@ -0,0 +1,140 @@
+package mqtt
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"time"
+)
+
+// Delivery of a published message, QoS 1 messages are done when the broker
+// acknowledges them.  Unacknowledged messages are resent after a reconnect.
+type Delivery struct {
+	Id      uint16
+	done    chan bool
+}
+
+func (d *Delivery) Wait(ctx context.Context) error {
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type MQTTClient struct {
+	*ws.ProtocolClient
+	cf      MQTTConfig
+	proto   *mqttProtocol
+}
+
+// Publish a message.  QoS 1 messages are resent after a reconnect until the
+// broker acknowledges them, QoS 0 messages are dropped when not connected.
+func (c *MQTTClient) Publish(topic string, payload []byte, qos byte, retain bool) *Delivery {
+	if qos > 1 {
+		qos = 1
+	}
+	d := &Delivery{
+		done: make(chan bool),
+	}
+	p := c.proto
+	if qos > 0 {
+		d.Id = p.makeId()
+	}
+	pkt := p.publishPacket(topic, payload, qos, d.Id, retain)
+	p.Lock()
+	connected := p.connected
+	if qos > 0 {
+		p.inflight[d.Id] = &inflight{pkt: pkt, delivery: d}
+	}
+	p.Unlock()
+	if connected {
+		p.sendPacket(pkt)
+	}
+	if qos == 0 {
+		close(d.done)
+	}
+	return d
+}
+
+func (c *MQTTClient) Close() {
+	if c.proto.isConnected() {
+		c.proto.sendPacket(&packet{typ: packetDisconnect})
+	}
+	c.ProtocolClient.Close()
+}
+
+// Subscribe to a topic filter with '+' and '#' wildcards, messages are
+// delivered with the max QoS.
+func (c *MQTTClient) SubscribeQoS(filter string, qos byte) *Subscription {
+	if qos > 1 {
+		qos = 1
+	}
+	if sub, ok := c.FindChannel(filter).(*Subscription); ok {
+		return sub
+	}
+	// create a new subscription.
+	sub := NewSubscription(filter, qos, c)
+	c.AddChannel(filter, sub)
+	return sub
+}
+
+func (c *MQTTClient) Subscribe(filter string) ws.Channel {
+	return c.SubscribeQoS(filter, 0)
+}
+
+type MQTTConfig struct {
+	ws.Config
+	ProtocolVersion   byte
+	ClientId          string  // random if empty
+	Username          string
+	Password          string
+	CleanSession      bool
+}
+
+var (
+	// ActivityTimeout is the keepalive interval.
+	DefaultMQTT = MQTTConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 60,
+			PingTimeout:     time.Second * 30,
+			ClientHeartbeat: true,
+			Subprotocols:    []string{"mqtt"},
+		},
+		ProtocolVersion:  MQTT311,
+		CleanSession:     true,
+	}
+)
+
+func (cf MQTTConfig) NewMQTTUrl(mqttUrl string) (*MQTTClient, error) {
+	u, err := url.Parse(mqttUrl)
+	if err != nil {
+		return nil, err
+	}
+	if cf.ClientId == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		cf.ClientId = "ws-go-" + hex.EncodeToString(id)
+	}
+	proto := &mqttProtocol{
+		cf: cf,
+		inflight: make(map[uint16]*inflight),
+		subacks: make(map[uint16]string),
+	}
+	return &MQTTClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		cf: cf,
+		proto: proto,
+	}, nil
+}
+
+func NewMQTTUrl(mqttUrl string) (*MQTTClient, error) {
+	return DefaultMQTT.NewMQTTUrl(mqttUrl)
+}
@@ -0,0 +1,163 @@
+package mqtt
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler, "mqtt")
+}
+
+func readPacket(t *testing.T, conn *websocket.Conn) *packet {
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	packets, _, err := decodePackets(msg)
+	if err != nil || len(packets) != 1 {
+		t.Errorf("bad packet: %v %v", packets, err)
+		return nil
+	}
+	return packets[0]
+}
+
+func writePacket(conn *websocket.Conn, p *packet) {
+	conn.WriteMessage(websocket.BinaryMessage, p.encode())
+}
+
+func TestPacketCodec(t *testing.T) {
+	body := bytes.Repeat([]byte{'x'}, 200)
+	buf := (&packet{typ: packetPublish, flags: 0x02, body: body}).encode()
+	buf = append(buf, (&packet{typ: packetPingresp}).encode()...)
+	// partial packet
+	packets, rest, err := decodePackets(buf[:100])
+	if err != nil || len(packets) != 0 || len(rest) != 100 {
+		t.Fatalf("expected partial packet, got %v %d %v", packets, len(rest), err)
+	}
+	packets, rest, err = decodePackets(buf)
+	if err != nil || len(packets) != 2 || len(rest) != 0 {
+		t.Fatalf("expected 2 packets, got %v %d %v", packets, len(rest), err)
+	}
+	if packets[0].typ != packetPublish || packets[0].flags != 0x02 || !bytes.Equal(packets[0].body, body) {
+		t.Fatalf("bad publish packet: %+v", packets[0])
+	}
+	if packets[1].typ != packetPingresp {
+		t.Fatalf("bad pingresp packet: %+v", packets[1])
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		filter  string
+		topic   string
+		match   bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"#", "$SYS/info", false},
+		{"$SYS/#", "$SYS/info", true},
+	}
+	for _, tt := range tests {
+		if MatchTopic(tt.filter, tt.topic) != tt.match {
+			t.Errorf("MatchTopic(%q, %q) != %v", tt.filter, tt.topic, tt.match)
+		}
+	}
+}
+
+func TestConnectFlags(t *testing.T) {
+	cf := DefaultMQTT
+	cf.ClientId = "test"
+	cf.Password = "secret"
+	p := &mqttProtocol{cf: cf}
+	packets, _, err := decodePackets(p.HandshakeFrame())
+	if err != nil || len(packets) != 1 {
+		t.Fatal(packets, err)
+	}
+	r := &reader{buf: packets[0].body}
+	r.string()
+	r.byte()
+	if flags := r.byte(); flags & 0xC0 != 0 {
+		t.Fatalf("password flag without username: %x", flags)
+	}
+	if strings.Contains(string(packets[0].body), "secret") {
+		t.Fatal("password sent without username")
+	}
+}
+
+func TestSubscribePublish(t *testing.T) {
+	done := make(chan struct{})
+	acked := make(chan uint16, 1)
+	u := testServer(t, func(conn *websocket.Conn) {
+		if p := readPacket(t, conn); p == nil || p.typ != packetConnect {
+			t.Errorf("expected CONNECT, got %+v", p)
+			return
+		}
+		writePacket(conn, &packet{typ: packetConnack, body: []byte{0, 0}})
+		for i := 0; i < 2; i++ {
+			p := readPacket(t, conn)
+			if p == nil || p.typ != packetSubscribe {
+				t.Errorf("expected SUBSCRIBE, got %+v", p)
+				return
+			}
+			writePacket(conn, &packet{typ: packetSuback, body: []byte{p.body[0], p.body[1], 0}})
+		}
+		var b bytes.Buffer
+		writeString(&b, "a/b")
+		writeUint16(&b, 7)
+		b.WriteString("hello")
+		writePacket(conn, &packet{typ: packetPublish, flags: 0x02, body: b.Bytes()})
+		if p := readPacket(t, conn); p != nil && p.typ == packetPuback {
+			r := &reader{buf: p.body}
+			acked <- r.uint16()
+		}
+		<-done
+	})
+	defer close(done)
+	client, err := NewMQTTUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 4)
+	handler := func(e ws.Event) {
+		got <- e
+	}
+	client.BindAllFunc(handler)
+	plus := client.SubscribeQoS("a/+", 1)
+	plus.BindAllFunc(handler)
+	hash := client.SubscribeQoS("a/#", 1)
+	hash.BindAllFunc(handler)
+	channels := make(map[string]int)
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-got:
+			if e.GetEvent() != "a/b" || e.GetDataString() != "hello" {
+				t.Fatalf("bad event: %+v", e)
+			}
+			channels[e.GetChannel()]++
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for publish")
+		}
+	}
+	if channels[""] != 1 || channels["a/+"] != 1 || channels["a/#"] != 1 {
+		t.Fatalf("expected one event per channel, got %v", channels)
+	}
+	select {
+	case id := <-acked:
+		if id != 7 {
+			t.Fatalf("expected PUBACK 7, got %d", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for PUBACK")
+	}
+}
@@ -0,0 +1,185 @@
+package mqtt
+
+import (
+	"bytes"
+	"errors"
+)
+
+// MQTT control packet types
+const (
+	packetConnect = 1 + iota
+	packetConnack
+	packetPublish
+	packetPuback
+	packetPubrec
+	packetPubrel
+	packetPubcomp
+	packetSubscribe
+	packetSuback
+	packetUnsubscribe
+	packetUnsuback
+	packetPingreq
+	packetPingresp
+	packetDisconnect
+)
+
+const (
+	MQTT311 = 4
+	MQTT5 = 5
+)
+
+var (
+	errShortPacket = errors.New("mqtt: short packet")
+	errBadLength = errors.New("mqtt: bad remaining length")
+)
+
+type packet struct {
+	typ    byte
+	flags  byte
+	body   []byte
+}
+
+func writeVarint(b *bytes.Buffer, n int) {
+	for {
+		c := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			c |= 0x80
+		}
+		b.WriteByte(c)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func writeUint16(b *bytes.Buffer, n uint16) {
+	b.WriteByte(byte(n >> 8))
+	b.WriteByte(byte(n))
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	writeUint16(b, uint16(len(s)))
+	b.WriteString(s)
+}
+
+func writeBytes(b *bytes.Buffer, buf []byte) {
+	writeUint16(b, uint16(len(buf)))
+	b.Write(buf)
+}
+
+func (p *packet) encode() []byte {
+	var b bytes.Buffer
+	b.WriteByte(p.typ << 4 | p.flags & 0x0f)
+	writeVarint(&b, len(p.body))
+	b.Write(p.body)
+	return b.Bytes()
+}
+
+// Decode the complete packets in buf, returns the left over bytes of a partial packet.
+func decodePackets(buf []byte) ([]*packet, []byte, error) {
+	var packets []*packet
+	for len(buf) >= 2 {
+		length := 0
+		mul := 1
+		i := 1
+		for {
+			if i >= len(buf) {
+				// partial length
+				return packets, buf, nil
+			}
+			if i > 4 {
+				return nil, nil, errBadLength
+			}
+			c := buf[i]
+			length += int(c & 0x7f) * mul
+			mul *= 128
+			i++
+			if c & 0x80 == 0 {
+				break
+			}
+		}
+		if len(buf) < i + length {
+			// partial packet
+			return packets, buf, nil
+		}
+		packets = append(packets, &packet{
+			typ: buf[0] >> 4,
+			flags: buf[0] & 0x0f,
+			body: buf[i:i+length],
+		})
+		buf = buf[i+length:]
+	}
+	return packets, buf, nil
+}
+
+// reader for packet bodies, the first error sticks.
+type reader struct {
+	buf  []byte
+	err  error
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil || len(r.buf) < 1 {
+		r.err = errShortPacket
+		return 0
+	}
+	c := r.buf[0]
+	r.buf = r.buf[1:]
+	return c
+}
+
+func (r *reader) uint16() uint16 {
+	if r.err != nil || len(r.buf) < 2 {
+		r.err = errShortPacket
+		return 0
+	}
+	n := uint16(r.buf[0]) << 8 | uint16(r.buf[1])
+	r.buf = r.buf[2:]
+	return n
+}
+
+func (r *reader) varint() int {
+	n := 0
+	mul := 1
+	for i := 0; i < 4; i++ {
+		c := r.byte()
+		n += int(c & 0x7f) * mul
+		if c & 0x80 == 0 {
+			return n
+		}
+		mul *= 128
+	}
+	r.err = errBadLength
+	return 0
+}
+
+func (r *reader) string() string {
+	n := int(r.uint16())
+	if r.err != nil || len(r.buf) < n {
+		r.err = errShortPacket
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+// MQTT 5 properties are skipped.
+func (r *reader) skipProperties(version byte) {
+	if version < MQTT5 {
+		return
+	}
+	n := r.varint()
+	if r.err != nil || len(r.buf) < n {
+		r.err = errShortPacket
+		return
+	}
+	r.buf = r.buf[n:]
+}
+
+func (r *reader) rest() []byte {
+	buf := r.buf
+	r.buf = nil
+	return buf
+}
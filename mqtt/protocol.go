@@ -0,0 +1,337 @@
+package mqtt
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"bytes"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+type inflight struct {
+	pkt       *packet
+	delivery  *Delivery
+}
+
+// Codec for MQTT control packets, packets are sent as binary messages.
+type mqttProtocol struct {
+	sync.Mutex
+	client      *ws.ProtocolClient
+	cf          MQTTConfig
+	nextId      uint16
+	inflight    map[uint16]*inflight
+	subacks     map[uint16]string
+	connected   bool
+	buf         []byte  // partial packet
+}
+
+func (p *mqttProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *mqttProtocol) makeId() uint16 {
+	p.Lock()
+	defer p.Unlock()
+	for {
+		p.nextId++
+		if p.nextId == 0 {
+			continue
+		}
+		if _, ok := p.inflight[p.nextId]; !ok {
+			return p.nextId
+		}
+	}
+}
+
+func (p *mqttProtocol) sendPacket(pkt *packet) {
+	p.client.SendBinaryMessage(pkt.encode())
+}
+
+func (p *mqttProtocol) isConnected() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.connected
+}
+
+func (p *mqttProtocol) Opened() bool {
+	// wait for CONNACK.
+	return false
+}
+
+func (p *mqttProtocol) Closed() {
+	p.Lock()
+	defer p.Unlock()
+	p.connected = false
+	p.subacks = make(map[uint16]string)
+	p.buf = nil
+}
+
+func (p *mqttProtocol) BinaryFrames() bool {
+	return true
+}
+
+func (p *mqttProtocol) HandshakeFrame() []byte {
+	var b bytes.Buffer
+	version := p.cf.ProtocolVersion
+	writeString(&b, "MQTT")
+	b.WriteByte(version)
+	var flags byte
+	if p.cf.CleanSession {
+		flags |= 0x02
+	}
+	if p.cf.Username != "" {
+		flags |= 0x80
+	}
+	if p.cf.Username != "" && p.cf.Password != "" {
+		// password flag is only allowed with a username.
+		flags |= 0x40
+	}
+	b.WriteByte(flags)
+	writeUint16(&b, uint16(p.cf.ActivityTimeout / time.Second))
+	if version >= MQTT5 {
+		// no properties
+		writeVarint(&b, 0)
+	}
+	writeString(&b, p.cf.ClientId)
+	if p.cf.Username != "" {
+		writeString(&b, p.cf.Username)
+	}
+	if flags & 0x40 != 0 {
+		writeString(&b, p.cf.Password)
+	}
+	return (&packet{typ: packetConnect, body: b.Bytes()}).encode()
+}
+
+func (p *mqttProtocol) publishPacket(topic string, payload []byte, qos byte, id uint16, retain bool) *packet {
+	var b bytes.Buffer
+	writeString(&b, topic)
+	if qos > 0 {
+		writeUint16(&b, id)
+	}
+	if p.cf.ProtocolVersion >= MQTT5 {
+		writeVarint(&b, 0)
+	}
+	b.Write(payload)
+	pkt := &packet{
+		typ: packetPublish,
+		flags: qos << 1,
+		body: b.Bytes(),
+	}
+	if retain {
+		pkt.flags |= 0x01
+	}
+	return pkt
+}
+
+// Events are published with QoS 0 to the event name or the channel.
+func (p *mqttProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	topic := e.GetEvent()
+	if topic == "" {
+		topic = e.GetChannel()
+	}
+	return p.publishPacket(topic, []byte(e.GetDataString()), 0, 0, false).encode(), nil
+}
+
+func (p *mqttProtocol) DecodeBinary(msg []byte) ([]ws.Event, error) {
+	p.Lock()
+	// packets can be split over or packed into websocket messages.
+	p.buf = append(p.buf, msg...)
+	packets, rest, err := decodePackets(p.buf)
+	if err != nil {
+		// the stream can't be resynced.
+		p.buf = nil
+		p.Unlock()
+		log.Println("MQTT bad packet:", err)
+		return nil, ws.ErrReconnect
+	}
+	p.buf = append([]byte(nil), rest...)
+	p.Unlock()
+	events := make([]ws.Event, 0, len(packets))
+	for _, pkt := range packets {
+		events = append(events, &Event{pkt: pkt})
+	}
+	return events, nil
+}
+
+func (p *mqttProtocol) DecodeEvents(msg []byte) ([]ws.Event, error) {
+	return p.DecodeBinary(msg)
+}
+
+func (p *mqttProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	return nil, fmt.Errorf("mqtt: only binary messages are supported")
+}
+
+func (p *mqttProtocol) handleConnack(r *reader) error {
+	r.byte()  // session present
+	code := r.byte()
+	if r.err != nil {
+		return r.err
+	}
+	if code != 0 {
+		reason := fmt.Sprintf("Connect refused: code: %d", code)
+		log.Println("MQTT", reason)
+		switch code {
+		case 3, 0x88, 0x89, 0x97, 0x9F:
+			// server unavailable/busy, try again later.
+			return ws.ErrDelayReconnect
+		}
+		return ws.NewError(reason, false, false, 0)
+	}
+	p.Lock()
+	p.connected = true
+	resend := make([]*packet, 0, len(p.inflight))
+	for _, m := range p.inflight {
+		resend = append(resend, m.pkt)
+	}
+	p.Unlock()
+	// resend unacknowledged messages.
+	for _, pkt := range resend {
+		pkt.flags |= 0x08
+		p.sendPacket(pkt)
+	}
+	// start keepalive pings and resubscribe.
+	p.client.Connected()
+	return nil
+}
+
+func (p *mqttProtocol) handlePublish(pkt *packet, r *reader) error {
+	event := &Event{
+		QoS: (pkt.flags >> 1) & 0x03,
+		Retain: pkt.flags & 0x01 != 0,
+		Dup: pkt.flags & 0x08 != 0,
+	}
+	event.Topic = r.string()
+	var id uint16
+	if event.QoS > 0 {
+		id = r.uint16()
+	}
+	r.skipProperties(p.cf.ProtocolVersion)
+	event.Payload = r.rest()
+	if r.err != nil {
+		return r.err
+	}
+	// send to the global channel and the subscriptions matching the topic.
+	p.client.Dispatch(event)
+	var ack bytes.Buffer
+	writeUint16(&ack, id)
+	switch event.QoS {
+	case 1:
+		p.sendPacket(&packet{typ: packetPuback, body: ack.Bytes()})
+	case 2:
+		p.sendPacket(&packet{typ: packetPubrec, body: ack.Bytes()})
+	}
+	return nil
+}
+
+// Subscriptions match the topic of publications with their filter.
+func (p *mqttProtocol) MatchChannel(filter string, e ws.Event) bool {
+	if e, ok := e.(*Event); ok {
+		return MatchTopic(filter, e.Topic)
+	}
+	return e.GetChannel() == filter
+}
+
+func (p *mqttProtocol) handlePuback(r *reader) {
+	id := r.uint16()
+	p.Lock()
+	m := p.inflight[id]
+	delete(p.inflight, id)
+	p.Unlock()
+	if m != nil {
+		close(m.delivery.done)
+	}
+}
+
+func (p *mqttProtocol) handleSuback(r *reader) {
+	id := r.uint16()
+	r.skipProperties(p.cf.ProtocolVersion)
+	codes := r.rest()
+	p.Lock()
+	filter, ok := p.subacks[id]
+	delete(p.subacks, id)
+	p.Unlock()
+	if !ok || len(codes) == 0 {
+		return
+	}
+	if codes[0] >= 0x80 {
+		log.Println("MQTT subscribe failed:", filter, ", code:", codes[0])
+		return
+	}
+	p.client.Subscribed(filter)
+}
+
+func (p *mqttProtocol) handlePacket(pkt *packet) error {
+	r := &reader{buf: pkt.body}
+	switch pkt.typ {
+	case packetConnack:
+		return p.handleConnack(r)
+	case packetPublish:
+		return p.handlePublish(pkt, r)
+	case packetPuback:
+		p.handlePuback(r)
+	case packetPubrel:
+		// complete QoS 2 delivery
+		id := r.uint16()
+		var b bytes.Buffer
+		writeUint16(&b, id)
+		p.sendPacket(&packet{typ: packetPubcomp, body: b.Bytes()})
+	case packetSuback:
+		p.handleSuback(r)
+	case packetPingresp:
+		p.client.Socket().HandlePong()
+	case packetDisconnect:
+		log.Println("MQTT disconnect from server.")
+		return ws.ErrDelayReconnect
+	}
+	return r.err
+}
+
+// All packets are handled here, publications are dispatched by the protocol.
+func (p *mqttProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	if e, ok := e.(*Event); ok && e.pkt != nil {
+		return ws.KindInternal, p.handlePacket(e.pkt)
+	}
+	return ws.KindInternal, nil
+}
+
+func (p *mqttProtocol) SubscribeFrame(filter string) []byte {
+	sub, _ := p.client.FindChannel(filter).(*Subscription)
+	if sub == nil || !p.isConnected() {
+		return nil
+	}
+	id := p.makeId()
+	p.Lock()
+	p.subacks[id] = filter
+	p.Unlock()
+	var b bytes.Buffer
+	writeUint16(&b, id)
+	if p.cf.ProtocolVersion >= MQTT5 {
+		writeVarint(&b, 0)
+	}
+	writeString(&b, filter)
+	b.WriteByte(sub.QoS)
+	return (&packet{typ: packetSubscribe, flags: 0x02, body: b.Bytes()}).encode()
+}
+
+func (p *mqttProtocol) UnsubscribeFrame(filter string) []byte {
+	if !p.isConnected() {
+		return nil
+	}
+	var b bytes.Buffer
+	writeUint16(&b, p.makeId())
+	if p.cf.ProtocolVersion >= MQTT5 {
+		writeVarint(&b, 0)
+	}
+	writeString(&b, filter)
+	return (&packet{typ: packetUnsubscribe, flags: 0x02, body: b.Bytes()}).encode()
+}
+
+func (p *mqttProtocol) PingFrame() []byte {
+	return (&packet{typ: packetPingreq}).encode()
+}
+
+func (p *mqttProtocol) PongFrame() []byte {
+	return nil
+}
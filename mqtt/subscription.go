@@ -0,0 +1,47 @@
+package mqtt
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+)
+
+type Subscription struct {
+	*ws.PublicChannel
+	Filter  string
+	QoS     byte
+}
+
+func (s *Subscription) UpdateClientState(connected bool) {
+	if connected {
+		// Client connected, (re)subscribe.
+		s.Subscribe()
+	} else {
+		s.SetActive(false)
+	}
+}
+
+// Publications are delivered with the filter they matched as the channel.
+func (s *Subscription) HandleEvent(event ws.Event) {
+	if e, ok := event.(*Event); ok && e.Channel != s.Filter {
+		c := *e
+		c.Channel = s.Filter
+		event = &c
+	}
+	s.PublicChannel.HandleEvent(event)
+}
+
+func (s *Subscription) Unsubscribe() {
+	s.PublicChannel.Unsubscribe()
+	s.SetActive(false)
+}
+
+func newSubscription(filter string, qos byte, proto *mqttProtocol) *Subscription {
+	return &Subscription{
+		PublicChannel: ws.NewPublicChannel(filter, proto.client),
+		Filter: filter,
+		QoS: qos,
+	}
+}
+
+func NewSubscription(filter string, qos byte, client *MQTTClient) *Subscription {
+	return newSubscription(filter, qos, client.proto)
+}
@@ -0,0 +1,27 @@
+package mqtt
+
+import (
+	"strings"
+)
+
+// Match a topic name against a subscription filter with '+' and '#' wildcards.
+func MatchTopic(filter string, topic string) bool {
+	// wildcards don't match topics starting with '$'
+	if strings.HasPrefix(topic, "$") && !strings.HasPrefix(filter, "$") {
+		return false
+	}
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if f != "+" && f != ts[i] {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
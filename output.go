@@ -0,0 +1,130 @@
+package main
+
+import (
+  ws "github.com/Neopallium/websocket-client-go/websocket"
+  "encoding/json"
+  "fmt"
+  "io"
+  "sync"
+  "time"
+)
+
+// Output formats
+const (
+  FormatText = "text"
+  FormatJSON = "json"
+  FormatPretty = "pretty"
+  FormatNDJSON = "ndjson"
+)
+
+type record struct {
+  Time    time.Time `json:"time"`
+  Type    string `json:"type"`
+  Channel string `json:"channel,omitempty"`
+  Event   string `json:"event,omitempty"`
+  State   string `json:"state,omitempty"`
+  Data    interface{} `json:"data,omitempty"`
+}
+
+// Prints events and connection states in the selected format.
+type output struct {
+  sync.Mutex
+  w      io.Writer
+  format string
+  count  int
+  closed bool
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+  switch format {
+  case FormatText, FormatJSON, FormatPretty, FormatNDJSON:
+  default:
+    return nil, fmt.Errorf("unknown output format: %s", format)
+  }
+  return &output{w: w, format: format}, nil
+}
+
+// Event data that is a JSON string is printed as JSON.
+func eventData(e ws.Event) interface{} {
+  data := e.GetData()
+  if s, ok := data.(string); ok && json.Valid([]byte(s)) {
+    return json.RawMessage(s)
+  }
+  return data
+}
+
+func (o *output) write(r *record) {
+  o.Lock()
+  defer o.Unlock()
+  if o.closed {
+    return
+  }
+  switch o.format {
+  case FormatText:
+    if r.Type == "state" {
+      fmt.Fprintln(o.w, "State:", r.State)
+    } else {
+      fmt.Fprintln(o.w, "Event:", r.Channel, r.Event, r.Data)
+    }
+    return
+  case FormatJSON:
+    // one JSON array, closed by close().
+    if o.count == 0 {
+      fmt.Fprint(o.w, "[\n")
+    } else {
+      fmt.Fprint(o.w, ",\n")
+    }
+  }
+  var buf []byte
+  var err error
+  if o.format == FormatPretty {
+    buf, err = json.MarshalIndent(r, "", "  ")
+  } else {
+    buf, err = json.Marshal(r)
+  }
+  if err != nil {
+    fmt.Fprintln(o.w, "Failed to encode:", err)
+    return
+  }
+  o.w.Write(buf)
+  if o.format != FormatJSON {
+    fmt.Fprintln(o.w)
+  }
+  o.count++
+}
+
+func (o *output) HandleEvent(e ws.Event) {
+  r := &record{
+    Time: time.Now(),
+    Type: "event",
+    Channel: e.GetChannel(),
+    Event: e.GetEvent(),
+  }
+  if o.format == FormatText {
+    r.Data = e.GetDataString()
+  } else {
+    r.Data = eventData(e)
+  }
+  o.write(r)
+}
+
+func (o *output) state(st ws.ConnState) {
+  o.write(&record{
+    Time: time.Now(),
+    Type: "state",
+    State: st.String(),
+  })
+}
+
+func (o *output) close() {
+  o.Lock()
+  defer o.Unlock()
+  o.closed = true
+  if o.format != FormatJSON {
+    return
+  }
+  if o.count == 0 {
+    fmt.Fprint(o.w, "[")
+  }
+  fmt.Fprint(o.w, "\n]\n")
+}
@@ -0,0 +1,82 @@
+package phoenix
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"sync"
+	"time"
+)
+
+const (
+	REJOIN_DELAY = time.Second * 5
+)
+
+type Channel struct {
+	*ws.PublicChannel
+	proto      *phoenixProtocol
+	topic      string
+	params     interface{}
+	mu         sync.Mutex
+	joinRef    string
+	connected  bool
+	joined     bool
+}
+
+func (c *Channel) JoinRef() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.joinRef
+}
+
+func (c *Channel) setJoinRef(ref string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.joinRef = ref
+}
+
+func (c *Channel) SetActive(active bool) {
+	c.mu.Lock()
+	c.joined = active
+	c.mu.Unlock()
+	c.PublicChannel.SetActive(active)
+}
+
+func (c *Channel) rejoinLater() {
+	time.AfterFunc(REJOIN_DELAY, func() {
+		c.mu.Lock()
+		rejoin := c.connected && !c.joined
+		c.mu.Unlock()
+		client := c.proto.client
+		if rejoin && client.FindChannel(c.topic) == ws.Channel(c) {
+			client.SendSubscribe(c.topic)
+		}
+	})
+}
+
+func (c *Channel) UpdateClientState(connected bool) {
+	c.mu.Lock()
+	c.connected = connected
+	if !connected {
+		c.joined = false
+	}
+	c.mu.Unlock()
+	c.PublicChannel.UpdateClientState(connected)
+}
+
+// Push an event to the channel, the reply can be waited for on the returned Push.
+func (c *Channel) Push(event string, payload interface{}) *Push {
+	return c.proto.push(c.topic, event, payload)
+}
+
+func newChannel(topic string, params interface{}, proto *phoenixProtocol) *Channel {
+	return &Channel{
+		PublicChannel: ws.NewPublicChannel(topic, proto.client),
+		proto: proto,
+		topic: topic,
+		params: params,
+	}
+}
+
+func NewChannel(topic string, params interface{}, client *PhoenixClient) *Channel {
+	return newChannel(topic, params, client.proto)
+}
@@ -0,0 +1,130 @@
+package phoenix
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Phoenix V2 message: [join_ref, ref, topic, event, payload]
+type Event struct {
+	JoinRef  string
+	Ref      string
+	Topic    string
+	Event    string
+	Payload  interface{}
+}
+
+func (e *Event) GetEvent() string {
+	return e.Event
+}
+
+// Phoenix channel events (e.g. "phx_reply").
+func (e *Event) Internal() bool {
+	return strings.HasPrefix(e.Event, "phx_")
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Event = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Topic
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Topic = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Payload
+}
+
+func (e *Event) SetData(data interface{}) {
+	e.Payload = data
+}
+
+func (e *Event) GetDataString() string {
+	// Normalize Payload as a string value.
+	switch e.Payload.(type) {
+	case string:
+		return e.Payload.(string)
+	default:
+		buf, err := json.Marshal(e.Payload)
+		if err != nil {
+			log.Fatal("JSON Marshaller failed:", err)
+		}
+		return string(buf)
+	}
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Payload = data
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (e *Event) MarshalJSON() ([]byte, error) {
+	payload := e.Payload
+	if payload == nil {
+		payload = struct{}{}
+	}
+	return json.Marshal([]interface{}{
+		nullString(e.JoinRef), nullString(e.Ref), e.Topic, e.Event, payload,
+	})
+}
+
+func (e *Event) UnmarshalJSON(buf []byte) error {
+	var msg []json.RawMessage
+	if err := json.Unmarshal(buf, &msg); err != nil {
+		return err
+	}
+	if len(msg) != 5 {
+		return fmt.Errorf("phoenix: bad message length: %d", len(msg))
+	}
+	var joinRef, ref *string
+	if err := json.Unmarshal(msg[0], &joinRef); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(msg[1], &ref); err != nil {
+		return err
+	}
+	if joinRef != nil {
+		e.JoinRef = *joinRef
+	}
+	if ref != nil {
+		e.Ref = *ref
+	}
+	if err := json.Unmarshal(msg[2], &e.Topic); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(msg[3], &e.Event); err != nil {
+		return err
+	}
+	return json.Unmarshal(msg[4], &e.Payload)
+}
+
+// Payload of a "phx_reply" event.
+type Reply struct {
+	Status   string `json:"status"`
+	Response interface{} `json:"response"`
+}
+
+func (r *Reply) Ok() bool {
+	return r.Status == "ok"
+}
+
+func parseReply(e *Event) *Reply {
+	r := &Reply{}
+	if payload, ok := e.Payload.(map[string]interface{}); ok {
+		r.Status, _ = payload["status"].(string)
+		r.Response = payload["response"]
+	}
+	return r
+}
@@ -0,0 +1,133 @@
+package phoenix
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDisconnected = errors.New("phoenix: disconnected before reply")
+)
+
+// Reply future for a pushed event.
+type Push struct {
+	Ref     string
+	proto   *phoenixProtocol
+	reply   chan *Reply
+}
+
+// Wait for the reply to the pushed event.
+func (p *Push) Wait(ctx context.Context) (*Reply, error) {
+	select {
+	case reply, ok := <-p.reply:
+		if !ok {
+			return nil, ErrDisconnected
+		}
+		return reply, nil
+	case <-ctx.Done():
+		p.proto.forget(p.Ref)
+		return nil, ctx.Err()
+	}
+}
+
+type PhoenixClient struct {
+	*ws.ProtocolClient
+	proto  *phoenixProtocol
+}
+
+// Push an event to a topic, the reply can be waited for on the returned Push.
+func (p *PhoenixClient) Push(topic string, event string, payload interface{}) *Push {
+	return p.proto.push(topic, event, payload)
+}
+
+// Join a topic with join params.
+func (p *PhoenixClient) Join(topic string, params interface{}) *Channel {
+	if ch, ok := p.FindChannel(topic).(*Channel); ok {
+		return ch
+	}
+	// create a new channel.
+	ch := NewChannel(topic, params, p)
+	p.AddChannel(topic, ch)
+	return ch
+}
+
+type PhoenixConfig struct {
+	ws.Config
+	Vsn               string
+}
+
+var (
+	DefaultPhoenix = PhoenixConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 30,
+			PingTimeout:     time.Second * 30,
+			ClientHeartbeat: true,
+		},
+		Vsn:             "2.0.0",
+	}
+)
+
+func (cf PhoenixConfig) socketUrl(socketUrl string, params url.Values) (*url.URL, error) {
+	u, err := url.Parse(socketUrl)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, "/websocket") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/websocket"
+	}
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("vsn", cf.Vsn)
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+func (cf PhoenixConfig) endpointUrls(urls []string, params url.Values) []string {
+	res := make([]string, 0, len(urls))
+	for _, rawUrl := range urls {
+		u, err := cf.socketUrl(rawUrl, params)
+		if err != nil {
+			log.Println("Bad endpoint url:", rawUrl, err)
+			continue
+		}
+		res = append(res, u.String())
+	}
+	return res
+}
+
+// Connect to a Phoenix socket endpoint (e.g. "wss://host/socket") with optional
+// connect params.
+func (cf PhoenixConfig) NewPhoenixUrl(socketUrl string, params url.Values) (*PhoenixClient, error) {
+	u, err := cf.socketUrl(socketUrl, params)
+	if err != nil {
+		return nil, err
+	}
+	// fallback endpoints need the same params.
+	cf.FallbackUrls = cf.endpointUrls(cf.FallbackUrls, params)
+	if resolver := cf.EndpointResolver; resolver != nil {
+		cf.EndpointResolver = func() ([]string, error) {
+			urls, err := resolver()
+			return cf.endpointUrls(urls, params), err
+		}
+	}
+	proto := &phoenixProtocol{
+		pending: make(map[string]*Push),
+	}
+	return &PhoenixClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		proto: proto,
+	}, nil
+}
+
+func NewPhoenixUrl(socketUrl string, params url.Values) (*PhoenixClient, error) {
+	return DefaultPhoenix.NewPhoenixUrl(socketUrl, params)
+}
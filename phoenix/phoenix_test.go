@@ -0,0 +1,101 @@
+package phoenix
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler) + "/socket"
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) *Event {
+	e := &Event{}
+	if err := conn.ReadJSON(e); err != nil {
+		t.Error(err)
+		return nil
+	}
+	return e
+}
+
+func TestEventJSON(t *testing.T) {
+	e := &Event{Ref: "1", Topic: "room:1", Event: "msg"}
+	buf, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != `[null,"1","room:1","msg",{}]` {
+		t.Fatalf("bad encoding: %s", buf)
+	}
+	var d Event
+	if err := json.Unmarshal([]byte(`["2","3","room:1","phx_reply",{"status":"ok","response":{"a":1}}]`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.JoinRef != "2" || d.Ref != "3" || d.Topic != "room:1" || d.Event != "phx_reply" {
+		t.Fatalf("bad decode: %+v", d)
+	}
+	if r := parseReply(&d); !r.Ok() {
+		t.Fatalf("bad reply: %+v", r)
+	}
+	if err := json.Unmarshal([]byte(`[null,null,"t"]`), &d); err == nil {
+		t.Fatal("expected error for short message")
+	}
+}
+
+func TestJoinAndPush(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		// bad frames are skipped.
+		conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+		join := readEvent(t, conn)
+		if join == nil || join.Event != "phx_join" || join.Topic != "room:1" {
+			t.Errorf("expected join, got %+v", join)
+			return
+		}
+		conn.WriteJSON(&Event{JoinRef: join.Ref, Ref: join.Ref, Topic: "room:1", Event: "phx_reply",
+			Payload: map[string]interface{}{"status": "ok", "response": map[string]interface{}{}}})
+		conn.WriteJSON(&Event{JoinRef: join.Ref, Topic: "room:1", Event: "new_msg", Payload: "hi"})
+		push := readEvent(t, conn)
+		if push == nil || push.Event != "shout" || push.JoinRef != join.Ref {
+			t.Errorf("expected push, got %+v", push)
+			return
+		}
+		conn.WriteJSON(&Event{JoinRef: join.Ref, Ref: push.Ref, Topic: "room:1", Event: "phx_reply",
+			Payload: map[string]interface{}{"status": "ok", "response": "pong"}})
+		<-done
+	})
+	defer close(done)
+	client, err := NewPhoenixUrl(u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 1)
+	ch := client.Join("room:1", map[string]interface{}{"user": "a"})
+	ch.BindFunc("new_msg", func(e ws.Event) {
+		got <- e
+	})
+	select {
+	case e := <-got:
+		if e.GetDataString() != "hi" {
+			t.Fatalf("bad event data: %q", e.GetDataString())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := ch.Push("shout", "hello").Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Ok() || reply.Response != "pong" {
+		t.Fatalf("bad reply: %+v", reply)
+	}
+}
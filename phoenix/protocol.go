@@ -0,0 +1,202 @@
+package phoenix
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+)
+
+// Codec for the Phoenix V2 serializer.
+type phoenixProtocol struct {
+	client      *ws.ProtocolClient
+	mu          sync.Mutex
+	ref         uint64
+	heartbeat   string
+	pending     map[string]*Push
+}
+
+func (p *phoenixProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *phoenixProtocol) makeRef() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ref++
+	return strconv.FormatUint(p.ref, 10)
+}
+
+func (p *phoenixProtocol) forget(ref string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, ref)
+}
+
+func (p *phoenixProtocol) Opened() bool {
+	// no handshake, start heartbeats and join channels.
+	return true
+}
+
+func (p *phoenixProtocol) Closed() {
+	// fail all pushes waiting for a reply.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ref, push := range p.pending {
+		close(push.reply)
+		delete(p.pending, ref)
+	}
+	p.heartbeat = ""
+}
+
+func (p *phoenixProtocol) HandshakeFrame() []byte {
+	return nil
+}
+
+func (p *phoenixProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (p *phoenixProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	event := &Event{}
+	if err := json.Unmarshal(msg, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (p *phoenixProtocol) channel(topic string) *Channel {
+	ch, _ := p.client.FindChannel(topic).(*Channel)
+	return ch
+}
+
+func (p *phoenixProtocol) handleReply(e *Event) {
+	p.mu.Lock()
+	push := p.pending[e.Ref]
+	delete(p.pending, e.Ref)
+	p.mu.Unlock()
+	if push != nil {
+		push.reply <- parseReply(e)
+	}
+}
+
+func (p *phoenixProtocol) Classify(event ws.Event) (ws.MessageKind, error) {
+	e := event.(*Event)
+	// heartbeat replies
+	if e.Topic == "phoenix" {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if e.Ref != "" && e.Ref == p.heartbeat {
+			p.heartbeat = ""
+			return ws.KindPong, nil
+		}
+		return ws.KindInternal, nil
+	}
+	ch := p.channel(e.Topic)
+	var joinRef string
+	if ch != nil {
+		joinRef = ch.JoinRef()
+		// drop events from an old join.
+		if e.JoinRef != "" && e.JoinRef != joinRef {
+			return ws.KindInternal, nil
+		}
+	}
+	switch e.Event {
+	case "phx_reply":
+		if ch != nil && e.Ref == joinRef {
+			reply := parseReply(e)
+			if reply.Ok() {
+				return ws.KindSubscribed, nil
+			}
+			log.Println("Phoenix join failed:", e.Topic, reply.Status, reply.Response)
+			ch.rejoinLater()
+			return ws.KindUnsubscribed, nil
+		}
+		p.handleReply(e)
+	case "phx_error":
+		log.Println("Phoenix channel error:", e.Topic)
+		if ch != nil {
+			ch.rejoinLater()
+		}
+		return ws.KindUnsubscribed, nil
+	case "phx_close":
+		return ws.KindUnsubscribed, nil
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *phoenixProtocol) SubscribeFrame(topic string) []byte {
+	ref := p.makeRef()
+	var params interface{}
+	if ch := p.channel(topic); ch != nil {
+		ch.setJoinRef(ref)
+		params = ch.params
+	}
+	buf, _ := p.EncodeEvent(&Event{
+		JoinRef: ref,
+		Ref: ref,
+		Topic: topic,
+		Event: "phx_join",
+		Payload: params,
+	})
+	return buf
+}
+
+func (p *phoenixProtocol) UnsubscribeFrame(topic string) []byte {
+	var joinRef string
+	if ch := p.channel(topic); ch != nil {
+		joinRef = ch.JoinRef()
+	}
+	buf, _ := p.EncodeEvent(&Event{
+		JoinRef: joinRef,
+		Ref: p.makeRef(),
+		Topic: topic,
+		Event: "phx_leave",
+	})
+	return buf
+}
+
+func (p *phoenixProtocol) PingFrame() []byte {
+	ref := p.makeRef()
+	p.mu.Lock()
+	p.heartbeat = ref
+	p.mu.Unlock()
+	buf, _ := p.EncodeEvent(&Event{
+		Ref: ref,
+		Topic: "phoenix",
+		Event: "heartbeat",
+	})
+	return buf
+}
+
+func (p *phoenixProtocol) PongFrame() []byte {
+	return nil
+}
+
+func (p *phoenixProtocol) NewChannel(topic string) ws.Channel {
+	return newChannel(topic, nil, p)
+}
+
+func (p *phoenixProtocol) push(topic string, event string, payload interface{}) *Push {
+	e := &Event{
+		Ref: p.makeRef(),
+		Topic: topic,
+		Event: event,
+		Payload: payload,
+	}
+	if ch := p.channel(topic); ch != nil {
+		e.JoinRef = ch.JoinRef()
+	}
+	push := &Push{
+		Ref: e.Ref,
+		proto: p,
+		reply: make(chan *Reply, 1),
+	}
+	p.mu.Lock()
+	p.pending[push.Ref] = push
+	p.mu.Unlock()
+	p.client.SendEvent(e)
+	return push
+}
@@ -0,0 +1,123 @@
+package pusher
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AUTH_TIMEOUT = time.Second * 10
+)
+
+// Signature for subscribing to a private or presence channel.
+type ChannelAuth struct {
+	Auth         string `json:"auth"`
+	ChannelData  string `json:"channel_data,omitempty"`
+}
+
+// Authorizer signs the subscription of the socket to a private or presence
+// channel.
+type Authorizer func(socketId string, channel string) (*ChannelAuth, error)
+
+func needsAuth(channel string) bool {
+	return strings.HasPrefix(channel, "private-") || strings.HasPrefix(channel, "presence-")
+}
+
+// Hex encoded HMAC-SHA256 of msg, used for channel auth, the HTTP API and
+// webhooks.
+func Sign(secret string, msg string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Check a hex encoded signature in constant time.
+func VerifySignature(secret string, msg string, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, msg)), []byte(signature))
+}
+
+// Hex encoded MD5 of a HTTP API request body.
+func BodyMD5(body []byte) string {
+	sum := md5.Sum(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// auth_signature of a HTTP API request, params must not include
+// auth_signature.
+func SignRequest(secret string, method string, path string, params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		// values are not escaped.
+		parts[i] = k + "=" + params.Get(k)
+	}
+	return Sign(secret, method + "\n" + path + "\n" + strings.Join(parts, "&"))
+}
+
+func sign(secret string, parts ...string) string {
+	return Sign(secret, strings.Join(parts, ":"))
+}
+
+// Sign subscriptions with the app secret.  Only for tools and tests, the secret
+// must not be shipped in clients.  userId is used for presence channels.
+func SecretAuthorizer(appKey string, secret string, userId string) Authorizer {
+	return func(socketId string, channel string) (*ChannelAuth, error) {
+		auth := &ChannelAuth{}
+		if strings.HasPrefix(channel, "presence-") {
+			buf, err := json.Marshal(map[string]string{"user_id": userId})
+			if err != nil {
+				return nil, err
+			}
+			auth.ChannelData = string(buf)
+			auth.Auth = appKey + ":" + sign(secret, socketId, channel, auth.ChannelData)
+		} else {
+			auth.Auth = appKey + ":" + sign(secret, socketId, channel)
+		}
+		return auth, nil
+	}
+}
+
+// Get the signature from the app's auth endpoint (POST socket_id &
+// channel_name).
+func EndpointAuthorizer(endpoint string, header http.Header) Authorizer {
+	client := &http.Client{Timeout: AUTH_TIMEOUT}
+	return func(socketId string, channel string) (*ChannelAuth, error) {
+		form := url.Values{}
+		form.Set("socket_id", socketId)
+		form.Set("channel_name", channel)
+		req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("pusher: auth endpoint: %s", resp.Status)
+		}
+		auth := &ChannelAuth{}
+		if err := json.NewDecoder(resp.Body).Decode(auth); err != nil {
+			return nil, err
+		}
+		return auth, nil
+	}
+}
@@ -3,6 +3,7 @@ package pusher
 import (
 	"encoding/json"
 	"log"
+	"strings"
 )
 
 type Event struct {
@@ -15,6 +16,11 @@ func (e *Event) GetEvent() string {
 	return e.Event
 }
 
+// Pusher protocol events (e.g. "pusher_internal:subscription_succeeded").
+func (e *Event) Internal() bool {
+	return strings.HasPrefix(e.Event, "pusher:") || strings.HasPrefix(e.Event, "pusher_internal:")
+}
+
 func (e *Event) SetEvent(event string) {
 	e.Event = event
 }
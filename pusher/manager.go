@@ -0,0 +1,277 @@
+package pusher
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"net/url"
+	"sync"
+)
+
+// Shares one PusherClient per url between consumers.  Channel subscriptions
+// are reference counted, pusher:unsubscribe is only sent when the last
+// consumer of the channel leaves.
+type Manager struct {
+	sync.Mutex
+	cf     PusherConfig
+	conns  map[string]*sharedConn
+}
+
+type sharedConn struct {
+	sync.RWMutex
+	url        string
+	client     *PusherClient
+	consumers  map[*Consumer]bool
+	// consumers per channel, subMu also orders the (un)subscribes.
+	subMu      sync.Mutex
+	subs       map[string]int
+	closed     bool
+}
+
+// Send events to the consumers, channel events only to the consumers of the
+// channel.
+func (s *sharedConn) HandleEvent(e ws.Event) {
+	s.RLock()
+	consumers := make([]*Consumer, 0, len(s.consumers))
+	for c := range s.consumers {
+		consumers = append(consumers, c)
+	}
+	s.RUnlock()
+	channel := e.GetChannel()
+	for _, c := range consumers {
+		if channel == "" || c.channels.Find(channel) != nil {
+			c.channels.HandleEvent(e)
+		}
+	}
+}
+
+func (m *Manager) consumer(key string, connect func() (*PusherClient, error)) (*Consumer, error) {
+	m.Lock()
+	defer m.Unlock()
+	conn := m.conns[key]
+	if conn == nil {
+		client, err := connect()
+		if err != nil {
+			return nil, err
+		}
+		conn = &sharedConn{
+			url: key,
+			client: client,
+			consumers: make(map[*Consumer]bool),
+			subs: make(map[string]int),
+		}
+		client.BindAll(conn)
+		m.conns[key] = conn
+	}
+	c := &Consumer{
+		m: m,
+		conn: conn,
+		subs: make(map[string]bool),
+	}
+	c.client = &consumerClient{conn.client, c}
+	c.channels = ws.NewChannels(c.client)
+	c.Binder = ws.NewBinder(c.channels)
+	c.channels.Add("", ws.NewPublicChannel("", c.client))
+	conn.Lock()
+	conn.consumers[c] = true
+	conn.Unlock()
+	return c, nil
+}
+
+func (m *Manager) acquire(conn *sharedConn, channel string) {
+	conn.subMu.Lock()
+	defer conn.subMu.Unlock()
+	if conn.closed {
+		return
+	}
+	conn.subs[channel]++
+	if conn.subs[channel] == 1 {
+		conn.client.Subscribe(channel)
+	}
+}
+
+func (m *Manager) release(conn *sharedConn, channel string) {
+	conn.subMu.Lock()
+	defer conn.subMu.Unlock()
+	if conn.closed || conn.subs[channel] == 0 {
+		return
+	}
+	conn.subs[channel]--
+	if conn.subs[channel] > 0 {
+		return
+	}
+	delete(conn.subs, channel)
+	conn.client.Unsubscribe(channel)
+}
+
+func (conn *sharedConn) close() {
+	conn.subMu.Lock()
+	conn.closed = true
+	conn.subMu.Unlock()
+	conn.client.Close()
+}
+
+// Remove the consumer, the connection is closed with its last consumer.
+func (m *Manager) detach(c *Consumer) {
+	conn := c.conn
+	m.Lock()
+	conn.Lock()
+	delete(conn.consumers, c)
+	empty := len(conn.consumers) == 0
+	conn.Unlock()
+	if empty && m.conns[conn.url] == conn {
+		delete(m.conns, conn.url)
+	}
+	m.Unlock()
+	if empty {
+		conn.close()
+	}
+}
+
+// Consumer for a Pusher url, connects if there is no shared connection yet.
+func (m *Manager) NewConsumerUrl(pusherUrl string) (*Consumer, error) {
+	u, err := url.Parse(pusherUrl)
+	if err != nil {
+		return nil, err
+	}
+	return m.consumer(u.String(), func() (*PusherClient, error) {
+		return newPusherClient(u, m.cf), nil
+	})
+}
+
+// Consumer for an app key on the default Pusher host.
+func (m *Manager) NewConsumer(appKey string) *Consumer {
+	u := appUrl(appKey)
+	c, _ := m.consumer(u.String(), func() (*PusherClient, error) {
+		return newPusherClient(u, m.cf), nil
+	})
+	return c
+}
+
+// Close all shared connections, their consumers are closed too.
+func (m *Manager) Close() {
+	m.Lock()
+	conns := make([]*sharedConn, 0, len(m.conns))
+	for key, conn := range m.conns {
+		conns = append(conns, conn)
+		delete(m.conns, key)
+	}
+	m.Unlock()
+	for _, conn := range conns {
+		conn.close()
+		conn.RLock()
+		consumers := make([]*Consumer, 0, len(conn.consumers))
+		for c := range conn.consumers {
+			consumers = append(consumers, c)
+		}
+		conn.RUnlock()
+		for _, c := range consumers {
+			c.mu.Lock()
+			c.closed = true
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (cf PusherConfig) NewManager() *Manager {
+	return &Manager{
+		cf: cf,
+		conns: make(map[string]*sharedConn),
+	}
+}
+
+func NewManager() *Manager {
+	return DefaultPusher.NewManager()
+}
+
+// Logical client of a shared connection, it only gets events of its own
+// channels.  A consumer joining an already subscribed channel doesn't get
+// the channel's subscription_succeeded event.
+type Consumer struct {
+	ws.Binder
+	m         *Manager
+	conn      *sharedConn
+	client    *consumerClient
+	channels  *ws.Channels
+	mu        sync.Mutex
+	subs      map[string]bool
+	closed    bool
+}
+
+// Channels of a consumer subscribe through the Manager.
+type consumerClient struct {
+	*PusherClient
+	c  *Consumer
+}
+
+func (cc *consumerClient) SendUnsubscribe(channel string) {
+	cc.c.Unsubscribe(channel)
+}
+
+func (cc *consumerClient) Subscribe(channel string) ws.Channel {
+	return cc.c.Subscribe(channel)
+}
+
+func (cc *consumerClient) Unsubscribe(channel string) {
+	cc.c.Unsubscribe(channel)
+}
+
+func (c *Consumer) Subscribe(channel string) ws.Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch := c.channels.Find(channel); ch != nil {
+		return ch
+	}
+	ch := ws.NewPublicChannel(channel, c.client)
+	if c.closed {
+		// no events after Close.
+		return ch
+	}
+	c.subs[channel] = true
+	c.channels.Add(channel, ch)
+	c.m.acquire(c.conn, channel)
+	return ch
+}
+
+func (c *Consumer) Unsubscribe(channel string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unsubscribe(channel)
+}
+
+func (c *Consumer) unsubscribe(channel string) {
+	if !c.subs[channel] {
+		return
+	}
+	delete(c.subs, channel)
+	c.channels.Remove(channel)
+	c.m.release(c.conn, channel)
+}
+
+// Release the consumer's channels and the shared connection.
+func (c *Consumer) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for name := range c.subs {
+		c.unsubscribe(name)
+	}
+	c.m.detach(c)
+}
+
+// Socket id of the shared connection.
+func (c *Consumer) SocketId() string {
+	return c.conn.client.SocketId()
+}
+
+// Send a client event on the shared connection.
+func (c *Consumer) Trigger(channel string, event string, data interface{}) error {
+	return c.conn.client.Trigger(channel, event, data)
+}
+
+func (c *Consumer) SendEvent(e ws.Event) {
+	c.conn.client.SendEvent(e)
+}
+
@@ -0,0 +1,144 @@
+package pusher
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/gorilla/websocket"
+
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Pusher server that reports the (un)subscribe frames of each connection.
+func managerServer(t *testing.T, conns *int32, frames chan<- string, sockets chan<- *websocket.Conn) string {
+	return testServer(t, func(conn *websocket.Conn) {
+		atomic.AddInt32(conns, 1)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"pusher:connection_established","data":"{\"socket_id\":\"1.2\",\"activity_timeout\":120}"}`))
+		if sockets != nil {
+			sockets <- conn
+		}
+		for {
+			var e subscribeEvent
+			if err := conn.ReadJSON(&e); err != nil {
+				frames <- "closed"
+				return
+			}
+			frames <- e.Event + " " + e.Data.Channel
+		}
+	})
+}
+
+func expectFrames(t *testing.T, frames <-chan string, expected ...string) {
+	t.Helper()
+	for _, f := range expected {
+		select {
+		case got := <-frames:
+			if got != f {
+				t.Fatalf("expected %q, got %q", f, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for", f)
+		}
+	}
+}
+
+func TestManagerShare(t *testing.T) {
+	var conns int32
+	frames := make(chan string, 10)
+	sockets := make(chan *websocket.Conn, 1)
+	u := managerServer(t, &conns, frames, sockets)
+	m := DefaultPusher.NewManager()
+	defer m.Close()
+	a, err := m.NewConsumerUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.NewConsumerUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan string, 10)
+	bind := func(name string, ch ws.Channel) {
+		ch.BindFunc("msg", func(e ws.Event) {
+			got <- name + " " + e.GetChannel()
+		})
+	}
+	bind("a", a.Subscribe("shared"))
+	bind("a", a.Subscribe("only-a"))
+	bind("b", b.Subscribe("shared"))
+	// one subscribe per channel, in any order.
+	subs := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case f := <-frames:
+			subs[f] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for subscribe")
+		}
+	}
+	if !subs["pusher:subscribe shared"] || !subs["pusher:subscribe only-a"] {
+		t.Fatalf("bad subscribes: %v", subs)
+	}
+	conn := <-sockets
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"msg","channel":"only-a","data":"1"}`))
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"msg","channel":"shared","data":"2"}`))
+	seen := make(map[string]bool)
+	for len(seen) < 3 {
+		select {
+		case e := <-got:
+			seen[e] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for events, got", seen)
+		}
+	}
+	if !seen["a only-a"] || !seen["a shared"] || !seen["b shared"] {
+		t.Fatalf("bad events: %v", seen)
+	}
+	// unsubscribed with the last consumer of the channel.
+	a.Unsubscribe("shared")
+	b.Unsubscribe("shared")
+	expectFrames(t, frames, "pusher:unsubscribe shared")
+	a.Close()
+	expectFrames(t, frames, "pusher:unsubscribe only-a")
+	b.Close()
+	expectFrames(t, frames, "closed")
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("expected one shared connection, got %d", n)
+	}
+}
+
+func TestManagerClose(t *testing.T) {
+	var conns int32
+	frames := make(chan string, 10)
+	u := managerServer(t, &conns, frames, nil)
+	m := DefaultPusher.NewManager()
+	a, _ := m.NewConsumerUrl(u)
+	b, _ := m.NewConsumerUrl(u)
+	a.Subscribe("x")
+	expectFrames(t, frames, "pusher:subscribe x")
+	m.Close()
+	expectFrames(t, frames, "closed")
+	// consumers of a closed manager are closed too.
+	a.Close()
+	b.Unsubscribe("x")
+	b.Subscribe("y")
+	b.Close()
+	// a new consumer gets a new connection, closing it closes the connection.
+	c, _ := m.NewConsumerUrl(u)
+	c.Subscribe("z")
+	expectFrames(t, frames, "pusher:subscribe z")
+	c.Close()
+	// the unsubscribe can be dropped by the close.
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case f := <-frames:
+			closed = f == "closed"
+		case <-timeout:
+			t.Fatal("timeout waiting for close")
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 2 {
+		t.Fatalf("expected two connections, got %d", n)
+	}
+}
@@ -0,0 +1,194 @@
+package pusher
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Codec for the Pusher protocol.
+type PusherProtocol struct {
+	// Signs private and presence channel subscriptions.
+	Authorizer  Authorizer
+	client      *ws.ProtocolClient
+	mu          sync.Mutex
+	socketId    string
+}
+
+func (p *PusherProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *PusherProtocol) NewChannel(channel string) ws.Channel {
+	return NewPublicChannel(channel, p.client)
+}
+
+// Socket id from the last connection_established.
+func (p *PusherProtocol) SocketId() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.socketId
+}
+
+func (p *PusherProtocol) HandshakeFrame() []byte {
+	// server sends pusher:connection_established.
+	return nil
+}
+
+func (p *PusherProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	return json.Marshal(&e)
+}
+
+func (p *PusherProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	event := &Event{}
+	if err := json.Unmarshal(msg, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (p *PusherProtocol) handleError(event ws.Event) error {
+	var msg struct {
+		Message string
+		Code    int64
+	}
+
+	data := event.GetDataString()
+	var err error
+	err = json.Unmarshal([]byte(data), &msg)
+	if err != nil {
+		log.Println("Failed to unmarshal error event:", event.GetEvent(), err)
+		return err
+	}
+	switch {
+	case 4000 <= msg.Code && msg.Code <= 4099:
+		log.Println("Connect failed websocket error: code:", msg.Code, ", message:", msg.Message)
+		return ws.ErrClosed
+	case 4100 <= msg.Code && msg.Code <= 4199:
+		log.Println("Try again (delayed reconnect): code:", msg.Code, ", message:", msg.Message)
+		return ws.ErrDelayReconnect
+	case 4200 <= msg.Code && msg.Code <= 4299:
+		log.Println("Reconnect (no delay): code:", msg.Code, ", message:", msg.Message)
+		return ws.ErrReconnect
+	default:
+		log.Println("Pusher error: code:", msg.Code, ", message:", msg.Message)
+	}
+	return nil
+}
+
+func (p *PusherProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	switch e.GetEvent() {
+	case "pusher:ping":
+		return ws.KindPing, nil
+	case "pusher:pong":
+		return ws.KindPong, nil
+	case "pusher:error":
+		return ws.KindEvent, p.handleError(e)
+	case "pusher:connection_established":
+		p.handleConnectionEstablished(e)
+		return ws.KindConnected, nil
+	case "pusher_internal:subscription_succeeded":
+		return ws.KindSubscribed, nil
+	}
+	return ws.KindEvent, nil
+}
+
+type connectionData struct {
+	SocketId         string `json:"socket_id"`
+	ActivityTimeout  int `json:"activity_timeout"`
+}
+
+func parseConnectionData(e ws.Event) *connectionData {
+	msg := &connectionData{}
+	if err := json.Unmarshal([]byte(e.GetDataString()), msg); err != nil {
+		log.Println("Failed to unmarshal:", e.GetEvent(), err)
+	}
+	return msg
+}
+
+func (p *PusherProtocol) handleConnectionEstablished(e ws.Event) {
+	msg := parseConnectionData(e)
+	p.mu.Lock()
+	p.socketId = msg.SocketId
+	p.mu.Unlock()
+}
+
+// Use the activity_timeout from connection_established.
+func (p *PusherProtocol) ActivityTimeout(e ws.Event) time.Duration {
+	return time.Duration(parseConnectionData(e).ActivityTimeout) * time.Second
+}
+
+type subData struct {
+	Channel     string `json:"channel"`
+	Auth        string `json:"auth,omitempty"`
+	ChannelData string `json:"channel_data,omitempty"`
+}
+
+func subscribeFrame(data subData) []byte {
+	buf, _ := json.Marshal(&Event{
+		Event: "pusher:subscribe",
+		Data: data,
+	})
+	return buf
+}
+
+// Private and presence channels are authorized in the background, the
+// Authorizer can make a HTTP request.
+func (p *PusherProtocol) SubscribeFrame(channel string) []byte {
+	data := subData{
+		Channel: channel,
+	}
+	if needsAuth(channel) && p.Authorizer != nil {
+		go p.authorize(p.SocketId(), data)
+		return nil
+	}
+	return subscribeFrame(data)
+}
+
+func (p *PusherProtocol) authorize(socketId string, data subData) {
+	auth, err := p.Authorizer(socketId, data.Channel)
+	if p.SocketId() != socketId || p.client.FindChannel(data.Channel) == nil {
+		// reconnected or unsubscribed, nothing to send.
+		return
+	}
+	if err != nil {
+		log.Println("Failed to authorize channel:", data.Channel, err)
+		p.client.Dispatch(&Event{
+			Event: "pusher:subscription_error",
+			Channel: data.Channel,
+			Data: map[string]interface{}{
+				"type": "AuthError",
+				"error": err.Error(),
+			},
+		})
+		return
+	}
+	data.Auth = auth.Auth
+	data.ChannelData = auth.ChannelData
+	p.client.SendMessage(subscribeFrame(data))
+}
+
+type unsubData struct {
+	Channel     string `json:"channel"`
+}
+
+func (p *PusherProtocol) UnsubscribeFrame(channel string) []byte {
+	buf, _ := p.EncodeEvent(&Event{
+		Event: "pusher:unsubscribe",
+		Data: unsubData{
+			Channel: channel,
+		},
+	})
+	return buf
+}
+
+func (p *PusherProtocol) PingFrame() []byte {
+	return []byte(`{"event":"pusher:ping","data":"{}"}`)
+}
+
+func (p *PusherProtocol) PongFrame() []byte {
+	return []byte(`{"event":"pusher:pong","data":"{}"}`)
+}
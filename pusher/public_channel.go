@@ -5,12 +5,10 @@ import (
 )
 
 type PublicChannel struct {
-	ws.PublicChannel
+	*ws.PublicChannel
 }
 
 func (c *PublicChannel) HandleEvent(event ws.Event) {
-	c.RLock()
-	defer c.RUnlock()
 	// mark channel as subscribed
 	if event.GetEvent() == "pusher_internal:subscription_succeeded" {
 		c.SetActive(true)
@@ -20,7 +18,6 @@ func (c *PublicChannel) HandleEvent(event ws.Event) {
 
 func NewPublicChannel(channel string, client ws.ChannelClient) *PublicChannel {
 	return &PublicChannel{
-		PublicChannel: *ws.NewPublicChannel(channel, client),
+		PublicChannel: ws.NewPublicChannel(channel, client),
 	}
 }
-
@@ -3,199 +3,80 @@ package pusher
 import (
 	ws "github.com/Neopallium/websocket-client-go/websocket"
 
-	"encoding/json"
+	"fmt"
 	"net/url"
+	"strings"
 	"time"
 	"strconv"
 	"log"
 )
 
 type PusherClient struct {
-	sock               *ws.Socket
-	channels           *ws.Channels
+	*ws.ProtocolClient
+	proto  *PusherProtocol
 }
 
-func (p *PusherClient) HandleDisconnect() bool {
-	p.channels.ConnectedState(false)
-	return true
+// Socket id of the current connection, needed to authorize channels.
+func (p *PusherClient) SocketId() string {
+	return p.proto.SocketId()
 }
 
-func (p *PusherClient) HandleConnected() {
-}
-
-func (p *PusherClient) handleError(event *Event) error {
-	var msg struct {
-		Message string
-		Code    int64
-	}
-
-	data := event.GetDataString()
-	var err error
-	err = json.Unmarshal([]byte(data), &msg)
-	if err != nil {
-		log.Println("Failed to unmarshal error event:", event.Event, err)
-		return err
-	}
-	switch {
-	case 4000 <= msg.Code && msg.Code <= 4099:
-		log.Println("Connect failed websocket error: code:", msg.Code, ", message:", msg.Message)
-		return ws.ErrClosed
-	case 4100 <= msg.Code && msg.Code <= 4199:
-		log.Println("Try again (delayed reconnect): code:", msg.Code, ", message:", msg.Message)
-		return ws.ErrDelayReconnect
-	case 4200 <= msg.Code && msg.Code <= 4299:
-		log.Println("Reconnect (no delay): code:", msg.Code, ", message:", msg.Message)
-		return ws.ErrReconnect
-	default:
-		log.Println("Pusher error: code:", msg.Code, ", message:", msg.Message)
-	}
-	return nil
-}
-
-func (p *PusherClient) handleConnectionEstablished(event *Event) error {
-	// process Connected information.
-	var msg struct {
-		SocketId         string `json:"socket_id"`
-		ActivityTimeout  int `json:"activity_timeout"`
-	}
-	if err := json.Unmarshal([]byte(event.GetDataString()), &msg); err != nil {
-		log.Println("Failed to unmarshal:", event.Event, err)
+// Send a client event, the event name must start with "client-" and the
+// channel must be private or presence.
+func (p *PusherClient) Trigger(channel string, event string, data interface{}) error {
+	if !strings.HasPrefix(event, "client-") {
+		return fmt.Errorf("pusher: client event %q must start with \"client-\"", event)
 	}
-	// update activity_timeout value
-	p.sock.SetActivityTimeout(time.Duration(msg.ActivityTimeout) * time.Second)
-	// subscribe to channels.
-	p.channels.ConnectedState(true)
-	return nil
-}
-
-func (p *PusherClient) HandleMessage(msg []byte) error {
-	var err error
-	var event Event
-
-	// parse into aux struct.
-	if err := json.Unmarshal(msg, &event); err != nil {
-		return err
-	}
-	// handle Websocket events.
-	switch event.Event {
-	case "pusher:ping":
-		p.sock.SendMessage([]byte(`{"event":"pusher:pong","data":"{}"}`))
-	case "pusher:pong":
-		p.sock.HandlePong()
-	case "pusher:error":
-		err = p.handleError(&event)
-	case "pusher:connection_established":
-		err = p.handleConnectionEstablished(&event)
-	}
-	p.channels.HandleEvent(&event)
-	return err
-}
-
-func (p *PusherClient) SendMessage(msg []byte) {
-	p.sock.SendMessage(msg)
-}
-
-func (p *PusherClient) SendPing() {
-	// send ping
-	p.sock.SendMessage([]byte(`{"event":"pusher:ping","data":"{}"}`))
-}
-
-func (p *PusherClient) SendEvent(e ws.Event) {
-	buf, err := json.Marshal(&e)
-	if err != nil {
-		log.Fatal("Error sending event:", err)
+	if !needsAuth(channel) {
+		return fmt.Errorf("pusher: client events need a private or presence channel: %q", channel)
 	}
-	p.sock.SendMessage(buf)
-}
-
-type subData struct {
-	Channel     string `json:"channel"`
-	Auth        string `json:"auth,omitempty"`
-	ChannelData string `json:"channel_data,omitempty"`
-}
-
-func (p *PusherClient) SendSubscribe(channel string) {
 	p.SendEvent(&Event{
-		Event: "pusher:subscribe",
-		Data: subData{
-			Channel: channel,
-		},
-	})
-}
-
-type unsubData struct {
-	Channel     string `json:"channel"`
-}
-
-func (p *PusherClient) SendUnsubscribe(channel string) {
-	p.SendEvent(&Event{
-		Event: "pusher:unsubscribe",
-		Data: unsubData{
-			Channel: channel,
-		},
+		Event: event,
+		Channel: channel,
+		Data: data,
 	})
+	return nil
 }
 
-func (p *PusherClient) Close() {
-	p.sock.Close()
+func (cf PusherConfig) setParams(u *url.URL) {
+	params := u.Query()
+	params.Set("protocol", strconv.Itoa(cf.Protocol))
+	params.Set("version", cf.Version)
+	params.Set("client", cf.Client)
+	u.RawQuery = params.Encode()
 }
 
-func (p *PusherClient) Subscribe(channel string) ws.Channel {
-	ch := p.channels.Find(channel)
-	if ch == nil {
-		// create a new channel.
-		ch = NewPublicChannel(channel, p)
-		p.channels.Add(channel, ch)
+func (cf PusherConfig) endpointUrls(urls []string) []string {
+	res := make([]string, 0, len(urls))
+	for _, rawUrl := range urls {
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			log.Println("Bad endpoint url:", rawUrl, err)
+			continue
+		}
+		cf.setParams(u)
+		res = append(res, u.String())
 	}
-	return ch
-}
-
-func (p *PusherClient) Unsubscribe(channel string) {
-	p.channels.Remove(channel)
-}
-
-func (p *PusherClient) Bind(event string, h ws.Handler) {
-	p.channels.Bind(event, h)
-}
-
-func (p *PusherClient) Unbind(event string, h ws.Handler) {
-	p.channels.Unbind(event, h)
-}
-
-func (p *PusherClient) BindFunc(event string, h func(ws.Event)) {
-	p.Bind(event, ws.HandlerFunc(h))
-}
-
-func (p *PusherClient) UnbindFunc(event string, h func(ws.Event)) {
-	p.Unbind(event, ws.HandlerFunc(h))
-}
-
-func (p *PusherClient) BindAll(h ws.Handler) {
-	p.Bind("", h)
-}
-
-func (p *PusherClient) UnbindAll(h ws.Handler) {
-	p.Unbind("", h)
-}
-
-func (p *PusherClient) BindAllFunc(h func(ws.Event)) {
-	p.Bind("", ws.HandlerFunc(h))
-}
-
-func (p *PusherClient) UnbindAllFunc(h func(ws.Event)) {
-	p.Unbind("", ws.HandlerFunc(h))
+	return res
 }
 
 func newPusherClient(u *url.URL, cf PusherConfig) *PusherClient {
-	params := u.Query()
-	params.Set("protocol", strconv.Itoa(cf.Protocol))
-	params.Set("version", cf.Version)
-	params.Set("client", cf.Client)
-	u.RawQuery = params.Encode()
-	p := &PusherClient{}
-	p.sock = ws.NewSocket(u, cf.Config, p)
-	p.channels = ws.NewChannels(p)
-	return p
+	cf.setParams(u)
+	// fallback endpoints need the same params.
+	cf.FallbackUrls = cf.endpointUrls(cf.FallbackUrls)
+	if resolver := cf.EndpointResolver; resolver != nil {
+		cf.EndpointResolver = func() ([]string, error) {
+			urls, err := resolver()
+			return cf.endpointUrls(urls), err
+		}
+	}
+	proto := &PusherProtocol{
+		Authorizer: cf.Authorizer,
+	}
+	return &PusherClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		proto: proto,
+	}
 }
 
 type PusherConfig struct {
@@ -203,6 +84,10 @@ type PusherConfig struct {
 	Client            string
 	Version           string
 	Protocol          int
+	// Signs private and presence channel subscriptions, called in the
+	// background.  Failures are sent to the handlers as
+	// "pusher:subscription_error" events.
+	Authorizer        Authorizer
 }
 
 var (
@@ -226,13 +111,16 @@ func (p PusherConfig) NewPusherUrl(pusherUrl string) (*PusherClient, error) {
 	return newPusherClient(u, p), nil
 }
 
-func (p PusherConfig) NewPusher(appKey string) (*PusherClient) {
-	u := &url.URL{
+func appUrl(appKey string) *url.URL {
+	return &url.URL{
 		Scheme: "wss",
 		Host: "ws.pusherapp.com:443",
 		Path: "/app/" + appKey,
 	}
-	return newPusherClient(u, p)
+}
+
+func (p PusherConfig) NewPusher(appKey string) (*PusherClient) {
+	return newPusherClient(appUrl(appKey), p)
 }
 
 func NewPusherUrl(url string) (*PusherClient, error) {
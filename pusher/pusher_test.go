@@ -0,0 +1,116 @@
+package pusher
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler)
+}
+
+type subscribeEvent struct {
+	Event  string
+	Data   subData
+}
+
+func TestSubscribeAuth(t *testing.T) {
+	done := make(chan struct{})
+	subs := make(chan subData, 3)
+	u := testServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"pusher:connection_established","data":"{\"socket_id\":\"1.2\",\"activity_timeout\":120}"}`))
+		for {
+			var e subscribeEvent
+			if err := conn.ReadJSON(&e); err != nil {
+				return
+			}
+			if e.Event == "pusher:subscribe" {
+				subs <- e.Data
+			}
+		}
+	})
+	slow := make(chan struct{})
+	defer close(slow)
+	defer close(done)
+	cf := DefaultPusher
+	cf.Authorizer = func(socketId string, channel string) (*ChannelAuth, error) {
+		switch channel {
+		case "private-slow":
+			<-slow
+		case "private-bad":
+			return nil, errors.New("forbidden")
+		}
+		return &ChannelAuth{Auth: "key:" + socketId}, nil
+	}
+	client, err := cf.NewPusherUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	errs := make(chan ws.Event, 1)
+	client.BindFunc("pusher:subscription_error", func(e ws.Event) {
+		errs <- e
+	})
+	// a slow authorizer doesn't hold up the other channels.
+	client.Subscribe("private-slow")
+	client.Subscribe("private-bad")
+	client.Subscribe("news")
+	client.Subscribe("private-ok")
+	got := make(map[string]string)
+	for len(got) < 2 {
+		select {
+		case sub := <-subs:
+			got[sub.Channel] = sub.Auth
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for subscribe, got", got)
+		}
+	}
+	if auth, ok := got["news"]; !ok || auth != "" {
+		t.Fatalf("expected unsigned news subscribe, got %v", got)
+	}
+	if got["private-ok"] != "key:1.2" {
+		t.Fatalf("expected signed private-ok subscribe, got %v", got)
+	}
+	select {
+	case e := <-errs:
+		if e.GetChannel() != "private-bad" || !strings.Contains(e.GetDataString(), "forbidden") {
+			t.Fatalf("bad subscription error: %s %s", e.GetChannel(), e.GetDataString())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for subscription error")
+	}
+}
+
+func TestConfigHandler(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	u := testServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"pusher:connection_established","data":"{\"socket_id\":\"1.2\",\"activity_timeout\":120}"}`))
+		<-done
+	})
+	events := make(chan string, 1)
+	cf := DefaultPusher
+	// bound before connecting, the first event can't be missed.
+	cf.Handler = ws.HandlerFunc(func(e ws.Event) {
+		events <- e.GetEvent()
+	})
+	client, err := cf.NewPusherUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	select {
+	case e := <-events:
+		if e != "pusher:connection_established" {
+			t.Fatalf("expected pusher:connection_established, got %s", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for pusher:connection_established")
+	}
+}
@@ -0,0 +1,223 @@
+package pusherd
+
+import (
+	"github.com/Neopallium/websocket-client-go/pusher"
+
+	"crypto/hmac"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MAX_TIMESTAMP_SKEW = time.Second * 600
+	MAX_TRIGGER_CHANNELS = 100
+	MAX_BATCH_EVENTS = 10
+	MAX_BODY_SIZE = 1024 * 1024
+)
+
+type triggerRequest struct {
+	Name      string `json:"name"`
+	Channels  []string `json:"channels"`
+	Channel   string `json:"channel"`
+	Data      string `json:"data"`
+	SocketId  string `json:"socket_id"`
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Check the auth_* params of a HTTP API request.
+func (a *app) verifyRequest(r *http.Request, body []byte) string {
+	params := r.URL.Query()
+	signature := params.Get("auth_signature")
+	params.Del("auth_signature")
+	if params.Get("auth_key") != a.Key {
+		return "Unknown auth_key"
+	}
+	ts, err := strconv.ParseInt(params.Get("auth_timestamp"), 10, 64)
+	if err != nil {
+		return "Invalid auth_timestamp"
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > MAX_TIMESTAMP_SKEW || skew < -MAX_TIMESTAMP_SKEW {
+		return "Timestamp expired"
+	}
+	if len(body) > 0 && params.Get("body_md5") != pusher.BodyMD5(body) {
+		return "Invalid body_md5"
+	}
+	expected := pusher.SignRequest(a.Secret, r.Method, r.URL.Path, params)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "Invalid signature"
+	}
+	return ""
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	// /apps/<id>/<resource>...
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/apps/"), "/", 2)
+	a := s.byId[parts[0]]
+	if a == nil || len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if msg := a.verifyRequest(r, body); msg != "" {
+		http.Error(w, msg, http.StatusUnauthorized)
+		return
+	}
+	resource := parts[1]
+	switch {
+	case r.Method == "POST" && resource == "events":
+		a.serveEvents(w, body)
+	case r.Method == "POST" && resource == "batch_events":
+		a.serveBatch(w, body)
+	case r.Method == "GET" && resource == "channels":
+		a.serveChannels(w, r)
+	case r.Method == "GET" && strings.HasPrefix(resource, "channels/"):
+		name := strings.TrimPrefix(resource, "channels/")
+		if strings.HasSuffix(name, "/users") {
+			a.serveUsers(w, strings.TrimSuffix(name, "/users"))
+		} else {
+			a.serveChannel(w, r, name)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *app) serveEvents(w http.ResponseWriter, body []byte) {
+	var req triggerRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Name == "" {
+		http.Error(w, "Invalid event", http.StatusBadRequest)
+		return
+	}
+	channels := req.Channels
+	if req.Channel != "" {
+		channels = append(channels, req.Channel)
+	}
+	if len(channels) == 0 || len(channels) > MAX_TRIGGER_CHANNELS {
+		http.Error(w, "Invalid number of channels", http.StatusBadRequest)
+		return
+	}
+	a.publish(channels, req.Name, req.Data, req.SocketId)
+	writeJSON(w, struct{}{})
+}
+
+func (a *app) serveBatch(w http.ResponseWriter, body []byte) {
+	var req struct {
+		Batch  []*triggerRequest `json:"batch"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || len(req.Batch) > MAX_BATCH_EVENTS {
+		http.Error(w, "Invalid batch", http.StatusBadRequest)
+		return
+	}
+	for _, e := range req.Batch {
+		if e.Name == "" || e.Channel == "" {
+			http.Error(w, "Invalid event in batch", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, e := range req.Batch {
+		a.publish([]string{e.Channel}, e.Name, e.Data, e.SocketId)
+	}
+	writeJSON(w, struct{}{})
+}
+
+type channelInfo struct {
+	Occupied           *bool `json:"occupied,omitempty"`
+	UserCount          *int `json:"user_count,omitempty"`
+	SubscriptionCount  *int `json:"subscription_count,omitempty"`
+}
+
+func hasInfo(r *http.Request, attr string) bool {
+	for _, v := range strings.Split(r.URL.Query().Get("info"), ",") {
+		if v == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *app) serveChannels(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("filter_by_prefix")
+	userCount := hasInfo(r, "user_count")
+	if userCount && !strings.HasPrefix(prefix, "presence-") {
+		http.Error(w, "user_count is only available for presence channels", http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	channels := make(map[string]*channelInfo)
+	for name, ch := range a.channels {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		info := &channelInfo{}
+		if userCount {
+			n := len(ch.users)
+			info.UserCount = &n
+		}
+		channels[name] = info
+	}
+	a.mu.Unlock()
+	writeJSON(w, map[string]interface{}{
+		"channels": channels,
+	})
+}
+
+func (a *app) serveChannel(w http.ResponseWriter, r *http.Request, name string) {
+	userCount := hasInfo(r, "user_count")
+	if userCount && !isPresence(name) {
+		http.Error(w, "user_count is only available for presence channels", http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	ch := a.channels[name]
+	occupied := ch != nil
+	info := &channelInfo{Occupied: &occupied}
+	if userCount {
+		n := 0
+		if ch != nil {
+			n = len(ch.users)
+		}
+		info.UserCount = &n
+	}
+	if hasInfo(r, "subscription_count") {
+		n := 0
+		if ch != nil {
+			n = len(ch.subs)
+		}
+		info.SubscriptionCount = &n
+	}
+	a.mu.Unlock()
+	writeJSON(w, info)
+}
+
+func (a *app) serveUsers(w http.ResponseWriter, name string) {
+	if !isPresence(name) {
+		http.Error(w, "Users are only available for presence channels", http.StatusBadRequest)
+		return
+	}
+	type user struct {
+		Id  string `json:"id"`
+	}
+	users := []user{}
+	a.mu.Lock()
+	if ch := a.channels[name]; ch != nil {
+		for id := range ch.users {
+			users = append(users, user{id})
+		}
+	}
+	a.mu.Unlock()
+	writeJSON(w, map[string]interface{}{
+		"users": users,
+	})
+}
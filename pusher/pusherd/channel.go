@@ -0,0 +1,103 @@
+package pusherd
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Presence channel member of a connection.
+type member struct {
+	UserId    string `json:"user_id"`
+	UserInfo  json.RawMessage `json:"user_info,omitempty"`
+}
+
+type channel struct {
+	name   string
+	subs   map[*conn]*member
+	// connections per user id and the user's info for presence channels.
+	users  map[string]int
+	info   map[string]json.RawMessage
+}
+
+func newChannel(name string) *channel {
+	return &channel{
+		name: name,
+		subs: make(map[*conn]*member),
+		users: make(map[string]int),
+		info: make(map[string]json.RawMessage),
+	}
+}
+
+func isPresence(name string) bool {
+	return strings.HasPrefix(name, "presence-")
+}
+
+func isPrivate(name string) bool {
+	return strings.HasPrefix(name, "private-") || isPresence(name)
+}
+
+// Add a subscriber, returns true if the member is a new user.
+func (ch *channel) add(c *conn, m *member) bool {
+	ch.subs[c] = m
+	if m == nil {
+		return false
+	}
+	ch.users[m.UserId]++
+	ch.info[m.UserId] = m.UserInfo
+	return ch.users[m.UserId] == 1
+}
+
+// Remove a subscriber, returns the member if it was the user's last
+// connection.
+func (ch *channel) remove(c *conn) *member {
+	m, ok := ch.subs[c]
+	if !ok {
+		return nil
+	}
+	delete(ch.subs, c)
+	if m == nil {
+		return nil
+	}
+	ch.users[m.UserId]--
+	if ch.users[m.UserId] > 0 {
+		return nil
+	}
+	delete(ch.users, m.UserId)
+	delete(ch.info, m.UserId)
+	return m
+}
+
+type presenceData struct {
+	Ids    []string `json:"ids"`
+	Hash   map[string]json.RawMessage `json:"hash"`
+	Count  int `json:"count"`
+}
+
+func (ch *channel) presence() interface{} {
+	ids := make([]string, 0, len(ch.users))
+	hash := make(map[string]json.RawMessage, len(ch.users))
+	for id := range ch.users {
+		ids = append(ids, id)
+		info := ch.info[id]
+		if info == nil {
+			info = json.RawMessage("null")
+		}
+		hash[id] = info
+	}
+	return map[string]interface{}{
+		"presence": &presenceData{
+			Ids: ids,
+			Hash: hash,
+			Count: len(ids),
+		},
+	}
+}
+
+// Send to all subscribers, except the connection with socketId if not empty.
+func (ch *channel) broadcast(msg []byte, socketId string) {
+	for c := range ch.subs {
+		if socketId == "" || c.socketId != socketId {
+			c.send(msg)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package pusherd
+
+import (
+	"github.com/Neopallium/websocket-client-go/pusher"
+	"github.com/gorilla/websocket"
+
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OUT_CHANNEL_SIZE = 100
+	WRITE_TIMEOUT = time.Second * 10
+	// Largest client frame, Pusher limits events to 10KB.
+	MAX_MESSAGE_SIZE = 16 * 1024
+)
+
+// Frame sent to clients.
+type message struct {
+	Event    string `json:"event"`
+	Channel  string `json:"channel,omitempty"`
+	Data     interface{} `json:"data"`
+	UserId   string `json:"user_id,omitempty"`
+}
+
+// Encode a frame, data that isn't a string is sent as a JSON string.
+func encode(event string, channel string, data interface{}) []byte {
+	if _, ok := data.(string); !ok {
+		buf, _ := json.Marshal(data)
+		data = string(buf)
+	}
+	buf, _ := json.Marshal(&message{
+		Event: event,
+		Channel: channel,
+		Data: data,
+	})
+	return buf
+}
+
+func errorFrame(code int, msg string) []byte {
+	data := map[string]interface{}{
+		"message": msg,
+		"code": nil,
+	}
+	if code > 0 {
+		data["code"] = code
+	}
+	buf, _ := json.Marshal(&message{
+		Event: "pusher:error",
+		Data: data,
+	})
+	return buf
+}
+
+// Client data can be an object or a JSON string.
+func decodeData(data interface{}, v interface{}) error {
+	str, ok := data.(string)
+	if !ok {
+		buf, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		str = string(buf)
+	}
+	return json.Unmarshal([]byte(str), v)
+}
+
+type conn struct {
+	srv       *Server
+	app       *app
+	ws        *websocket.Conn
+	socketId  string
+	out       chan []byte
+	done      chan bool
+	once      sync.Once
+	// subscribed channels, guarded by app.mu
+	channels  map[string]bool
+}
+
+func newConn(srv *Server, a *app, ws *websocket.Conn) *conn {
+	return &conn{
+		srv: srv,
+		app: a,
+		ws: ws,
+		socketId: srv.nextSocketId(),
+		out: make(chan []byte, OUT_CHANNEL_SIZE),
+		done: make(chan bool),
+		channels: make(map[string]bool),
+	}
+}
+
+func (c *conn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+// Queue a frame, slow clients are disconnected.
+func (c *conn) send(msg []byte) {
+	select {
+	case c.out <- msg:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+func (c *conn) sendError(code int, msg string) {
+	c.send(errorFrame(code, msg))
+}
+
+func (c *conn) writeLoop() {
+	for {
+		select {
+		case msg := <-c.out:
+			c.ws.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+			if err := c.ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *conn) readLoop() {
+	defer c.app.disconnect(c)
+	defer c.close()
+	timeout := c.srv.cf.ActivityTimeout + c.srv.cf.PingTimeout
+	c.ws.SetReadLimit(MAX_MESSAGE_SIZE)
+	c.ws.SetPingHandler(func(data string) error {
+		c.ws.SetReadDeadline(time.Now().Add(timeout))
+		return c.ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(WRITE_TIMEOUT))
+	})
+	for {
+		c.ws.SetReadDeadline(time.Now().Add(timeout))
+		_, buf, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		e := &pusher.Event{}
+		if err := json.Unmarshal(buf, e); err != nil {
+			c.sendError(0, "Invalid JSON")
+			continue
+		}
+		c.handleEvent(e)
+	}
+}
+
+func (c *conn) run() {
+	data := map[string]interface{}{
+		"socket_id": c.socketId,
+		"activity_timeout": int(c.srv.cf.ActivityTimeout / time.Second),
+	}
+	c.send(encode("pusher:connection_established", "", data))
+	go c.writeLoop()
+	c.readLoop()
+}
+
+func (c *conn) handleEvent(e *pusher.Event) {
+	switch {
+	case e.Event == "pusher:ping":
+		c.send(encode("pusher:pong", "", "{}"))
+	case e.Event == "pusher:subscribe":
+		c.subscribe(e)
+	case e.Event == "pusher:unsubscribe":
+		var d struct {
+			Channel  string `json:"channel"`
+		}
+		if err := decodeData(e.Data, &d); err == nil {
+			c.app.unsubscribe(c, d.Channel)
+		}
+	case strings.HasPrefix(e.Event, "client-"):
+		c.clientEvent(e)
+	}
+}
+
+// Check the subscription signature and decode the presence member.
+func (c *conn) authorize(name string, auth string, channelData string) (*member, error) {
+	msg := c.socketId + ":" + name
+	if isPresence(name) {
+		msg += ":" + channelData
+	}
+	expected := c.app.Key + ":" + pusher.Sign(c.app.Secret, msg)
+	if !hmac.Equal([]byte(auth), []byte(expected)) {
+		return nil, fmt.Errorf("Invalid signature: Expected HMAC SHA256 hex digest of %s, but got %s", msg, auth)
+	}
+	if !isPresence(name) {
+		return nil, nil
+	}
+	var d struct {
+		UserId    interface{} `json:"user_id"`
+		UserInfo  json.RawMessage `json:"user_info"`
+	}
+	if err := json.Unmarshal([]byte(channelData), &d); err != nil || d.UserId == nil {
+		return nil, fmt.Errorf("Invalid channel_data, user_id is required")
+	}
+	return &member{
+		UserId: fmt.Sprint(d.UserId),
+		UserInfo: d.UserInfo,
+	}, nil
+}
+
+func (c *conn) subscribe(e *pusher.Event) {
+	var d struct {
+		Channel      string `json:"channel"`
+		Auth         string `json:"auth"`
+		ChannelData  string `json:"channel_data"`
+	}
+	if err := decodeData(e.Data, &d); err != nil || d.Channel == "" {
+		c.sendError(0, "Invalid subscribe data")
+		return
+	}
+	var m *member
+	if isPrivate(d.Channel) {
+		var err error
+		if m, err = c.authorize(d.Channel, d.Auth, d.ChannelData); err != nil {
+			c.sendError(0, err.Error())
+			return
+		}
+	}
+	c.app.subscribe(c, d.Channel, m)
+}
+
+func (c *conn) clientEvent(e *pusher.Event) {
+	if !c.app.ClientEvents {
+		c.sendError(0, "Client events are not enabled for this app")
+		return
+	}
+	if !isPrivate(e.Channel) {
+		c.sendError(0, "Client events need a private or presence channel")
+		return
+	}
+	c.app.clientEvent(c, e)
+}
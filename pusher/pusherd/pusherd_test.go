@@ -0,0 +1,141 @@
+package pusherd
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/pusher"
+	"github.com/Neopallium/websocket-client-go/pusher/server"
+	"github.com/gorilla/websocket"
+
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T) (*Server, string) {
+	srv := NewServer(App{Id: "1", Key: "key", Secret: "secret", ClientEvents: true})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	t.Cleanup(srv.Close)
+	return srv, ts.URL
+}
+
+func wsUrl(u string) string {
+	return "ws" + strings.TrimPrefix(u, "http") + "/app/key"
+}
+
+func apiUrl(u string, secret string) string {
+	return strings.Replace(u, "http://", "http://key:" + secret + "@", 1) + "/apps/1"
+}
+
+func expectEvent(t *testing.T, events <-chan string, expected string) {
+	t.Helper()
+	select {
+	case got := <-events:
+		if got != expected {
+			t.Fatalf("expected %q, got %q", expected, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for", expected)
+	}
+}
+
+func presenceClient(t *testing.T, u string, userId string) (*pusher.PusherClient, <-chan string) {
+	cf := pusher.DefaultPusher
+	cf.Authorizer = pusher.SecretAuthorizer("key", "secret", userId)
+	client, err := cf.NewPusherUrl(wsUrl(u))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan string, 10)
+	client.Subscribe("presence-room").BindAllFunc(func(e ws.Event) {
+		events <- e.GetEvent() + " " + e.GetDataString()
+	})
+	return client, events
+}
+
+func TestPresenceAndClientEvents(t *testing.T) {
+	_, u := testServer(t)
+	alice, aliceEvents := presenceClient(t, u, "alice")
+	defer alice.Close()
+	expectEvent(t, aliceEvents, `pusher_internal:subscription_succeeded {"presence":{"ids":["alice"],"hash":{"alice":null},"count":1}}`)
+	bob, bobEvents := presenceClient(t, u, "bob")
+	expectEvent(t, aliceEvents, `pusher_internal:member_added {"user_id":"bob"}`)
+	<-bobEvents
+	if err := bob.Trigger("presence-room", "client-hi", "yo"); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, aliceEvents, "client-hi yo")
+	api, err := server.NewServerUrl(apiUrl(u, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := api.Users("presence-room")
+	if err != nil || len(users) != 2 {
+		t.Fatalf("expected 2 users, got %v %v", users, err)
+	}
+	if err := api.Trigger("presence-room", "news", "1"); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, aliceEvents, "news 1")
+	bob.Close()
+	expectEvent(t, aliceEvents, `pusher_internal:member_removed {"user_id":"bob"}`)
+}
+
+func TestAPIAuth(t *testing.T) {
+	_, u := testServer(t)
+	api, _ := server.NewServerUrl(apiUrl(u, "wrong"))
+	err := api.Trigger("news", "x", "1")
+	if e, ok := err.(*server.RequestError); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %v", err)
+	}
+	body := bytes.Repeat([]byte("x"), MAX_BODY_SIZE + 1)
+	resp, err := http.Post(u + "/apps/1/events", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", resp.StatusCode)
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	_, u := testServer(t)
+	conn, _, err := websocket.DefaultDialer.Dial(wsUrl(u), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// connection_established
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	conn.WriteMessage(websocket.TextMessage, bytes.Repeat([]byte("x"), MAX_MESSAGE_SIZE + 1))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if e, ok := err.(interface{ Timeout() bool }); ok && e.Timeout() {
+			t.Fatal("connection not closed")
+		}
+		return
+	}
+}
+
+func TestSocketIds(t *testing.T) {
+	srv := NewServer()
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := srv.nextSocketId()
+		parts := strings.Split(id, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" || seen[id] {
+			t.Fatalf("bad socket id: %s", id)
+		}
+		seen[id] = true
+	}
+}
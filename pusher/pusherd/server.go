@@ -0,0 +1,256 @@
+package pusherd
+
+import (
+	"github.com/Neopallium/websocket-client-go/pusher"
+	"github.com/gorilla/websocket"
+
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// App credentials, clients connect to /app/<Key> and the HTTP API is at
+// /apps/<Id>.
+type App struct {
+	Id            string
+	Key           string
+	Secret        string
+	// Allow client-* events on private and presence channels.
+	ClientEvents  bool
+}
+
+type app struct {
+	App
+	mu        sync.Mutex
+	channels  map[string]*channel
+	conns     map[*conn]bool
+}
+
+func newApp(a App) *app {
+	return &app{
+		App: a,
+		channels: make(map[string]*channel),
+		conns: make(map[*conn]bool),
+	}
+}
+
+func (a *app) connect(c *conn) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.conns[c] = true
+}
+
+func (a *app) subscribe(c *conn, name string, m *member) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if c.channels[name] {
+		return
+	}
+	ch := a.channels[name]
+	if ch == nil {
+		ch = newChannel(name)
+		a.channels[name] = ch
+	}
+	c.channels[name] = true
+	added := ch.add(c, m)
+	var data interface{} = "{}"
+	if isPresence(name) {
+		data = ch.presence()
+	}
+	c.send(encode("pusher_internal:subscription_succeeded", name, data))
+	if added {
+		ch.broadcast(encode("pusher_internal:member_added", name, m), c.socketId)
+	}
+}
+
+// Remove the subscription, call with a.mu locked.
+func (a *app) remove(c *conn, name string) {
+	ch := a.channels[name]
+	if ch == nil || !c.channels[name] {
+		return
+	}
+	delete(c.channels, name)
+	if m := ch.remove(c); m != nil {
+		ch.broadcast(encode("pusher_internal:member_removed", name, map[string]string{
+			"user_id": m.UserId,
+		}), "")
+	}
+	if len(ch.subs) == 0 {
+		delete(a.channels, name)
+	}
+}
+
+func (a *app) unsubscribe(c *conn, name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.remove(c, name)
+}
+
+func (a *app) disconnect(c *conn) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name := range c.channels {
+		a.remove(c, name)
+	}
+	delete(a.conns, c)
+}
+
+func (a *app) clientEvent(c *conn, e *pusher.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ch := a.channels[e.Channel]
+	if ch == nil || !c.channels[e.Channel] {
+		c.sendError(0, "Client event rejected, not subscribed to channel: " + e.Channel)
+		return
+	}
+	msg := &message{
+		Event: e.Event,
+		Channel: e.Channel,
+		Data: e.Data,
+	}
+	if m := ch.subs[c]; m != nil {
+		msg.UserId = m.UserId
+	}
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	ch.broadcast(buf, c.socketId)
+}
+
+// Publish to channels, except to the connection with socketId.
+func (a *app) publish(channels []string, event string, data string, socketId string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, name := range channels {
+		if ch := a.channels[name]; ch != nil {
+			ch.broadcast(encode(event, name, data), socketId)
+		}
+	}
+}
+
+func (a *app) closeAll() {
+	a.mu.Lock()
+	conns := make([]*conn, 0, len(a.conns))
+	for c := range a.conns {
+		conns = append(conns, c)
+	}
+	a.mu.Unlock()
+	for _, c := range conns {
+		c.close()
+	}
+}
+
+// Pusher protocol 7 server with the HTTP API for events and channel queries.
+type Server struct {
+	cf        ServerConfig
+	upgrader  websocket.Upgrader
+	byKey     map[string]*app
+	byId      map[string]*app
+	nextId    uint64
+}
+
+// Socket ids sign channel subscriptions, the random part must not be
+// guessable.
+func (s *Server) nextSocketId() string {
+	var buf [4]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%d.%d", atomic.AddUint64(&s.nextId, 1), binary.BigEndian.Uint32(buf[:]) & 0x7fffffff)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/app/"):
+		s.serveWebsocket(w, r, strings.TrimPrefix(r.URL.Path, "/app/"))
+	case strings.HasPrefix(r.URL.Path, "/apps/"):
+		s.serveAPI(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request, key string) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	a := s.byKey[key]
+	if a == nil {
+		s.reject(ws, 4001, "App key " + key + " not in this cluster")
+		return
+	}
+	if protocol := r.URL.Query().Get("protocol"); protocol != "" {
+		if v, _ := strconv.Atoi(protocol); v != 7 {
+			s.reject(ws, 4007, "Unsupported protocol version")
+			return
+		}
+	}
+	c := newConn(s, a, ws)
+	a.connect(c)
+	c.run()
+}
+
+// Send the error and close the connection with its code.
+func (s *Server) reject(ws *websocket.Conn, code int, msg string) {
+	ws.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+	ws.WriteMessage(websocket.TextMessage, errorFrame(code, msg))
+	ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, msg))
+	ws.Close()
+}
+
+// Disconnect all clients.  The http.Server must be closed by the caller.
+func (s *Server) Close() {
+	for _, a := range s.byKey {
+		a.closeAll()
+	}
+}
+
+type ServerConfig struct {
+	// Sent to clients, they ping after this long without messages.
+	ActivityTimeout  time.Duration
+	// Extra time for the client's ping before an idle connection is closed.
+	PingTimeout      time.Duration
+	// Check the Origin header, all origins are allowed if nil.
+	CheckOrigin      func(r *http.Request) bool
+}
+
+var (
+	DefaultServer = ServerConfig{
+		ActivityTimeout: time.Second * 120,
+		PingTimeout:     time.Second * 30,
+	}
+)
+
+func (cf ServerConfig) NewServer(apps ...App) *Server {
+	checkOrigin := cf.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = func(r *http.Request) bool { return true }
+	}
+	s := &Server{
+		cf: cf,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin,
+		},
+		byKey: make(map[string]*app),
+		byId: make(map[string]*app),
+	}
+	for _, a := range apps {
+		state := newApp(a)
+		s.byKey[a.Key] = state
+		s.byId[a.Id] = state
+	}
+	return s
+}
+
+func NewServer(apps ...App) *Server {
+	return DefaultServer.NewServer(apps...)
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/pusher"
+
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+const (
+	CHANNEL_OCCUPIED = "channel_occupied"
+	CHANNEL_VACATED = "channel_vacated"
+	MEMBER_ADDED = "member_added"
+	MEMBER_REMOVED = "member_removed"
+	CLIENT_EVENT = "client_event"
+	// Largest webhook body accepted by WebhookHandler.
+	MAX_WEBHOOK_SIZE = 1024 * 1024
+)
+
+var (
+	ErrWebhookKey = errors.New("pusher: webhook key doesn't match")
+	ErrWebhookSignature = errors.New("pusher: invalid webhook signature")
+)
+
+// One event of a webhook, only the fields of the event's type are set.
+type WebhookEvent struct {
+	Name      string `json:"name"`
+	Channel   string `json:"channel"`
+	Event     string `json:"event,omitempty"`
+	Data      string `json:"data,omitempty"`
+	SocketId  string `json:"socket_id,omitempty"`
+	UserId    string `json:"user_id,omitempty"`
+}
+
+func (e *WebhookEvent) GetEvent() string {
+	return e.Name
+}
+
+func (e *WebhookEvent) SetEvent(event string) {
+	e.Name = event
+}
+
+func (e *WebhookEvent) GetChannel() string {
+	return e.Channel
+}
+
+func (e *WebhookEvent) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *WebhookEvent) GetData() interface{} {
+	return e.Data
+}
+
+func (e *WebhookEvent) SetData(data interface{}) {
+	if s, ok := data.(string); ok {
+		e.Data = s
+	}
+}
+
+func (e *WebhookEvent) GetDataString() string {
+	return e.Data
+}
+
+func (e *WebhookEvent) SetDataString(data string) {
+	e.Data = data
+}
+
+type Webhook struct {
+	TimeMs  int64 `json:"time_ms"`
+	Events  []*WebhookEvent `json:"events"`
+}
+
+// Check the X-Pusher-Key and X-Pusher-Signature headers of a webhook body.
+func (s *Server) VerifyWebhook(header http.Header, body []byte) error {
+	if header.Get("X-Pusher-Key") != s.key {
+		return ErrWebhookKey
+	}
+	if !pusher.VerifySignature(s.secret, string(body), header.Get("X-Pusher-Signature")) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// Verify and decode a webhook.
+func (s *Server) ParseWebhook(header http.Header, body []byte) (*Webhook, error) {
+	if err := s.VerifyWebhook(header, body); err != nil {
+		return nil, err
+	}
+	hook := &Webhook{}
+	if err := json.Unmarshal(body, hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Read, verify and decode the webhook request.  The body isn't limited, wrap
+// it with http.MaxBytesReader.
+func (s *Server) WebhookRequest(r *http.Request) (*Webhook, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return s.ParseWebhook(r.Header, body)
+}
+
+// http.Handler for webhooks.  Bind handlers to the webhook names
+// (CHANNEL_OCCUPIED, MEMBER_ADDED, CLIENT_EVENT, ...), the events are
+// *WebhookEvent values.
+type WebhookHandler struct {
+	sync.RWMutex
+	server    *Server
+	handlers  map[string][]ws.Handler
+}
+
+func (h *WebhookHandler) Bind(name string, handler ws.Handler) {
+	h.Lock()
+	defer h.Unlock()
+	h.handlers[name] = append(h.handlers[name], handler)
+}
+
+// Handlers are compared with ==, HandlerFunc values can't be removed.
+func (h *WebhookHandler) Unbind(name string, handler ws.Handler) {
+	if handler == nil || !reflect.TypeOf(handler).Comparable() {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	list := h.handlers[name]
+	res := make([]ws.Handler, 0, len(list))
+	for _, f := range list {
+		if f != handler {
+			res = append(res, f)
+		}
+	}
+	h.handlers[name] = res
+}
+
+func (h *WebhookHandler) BindFunc(name string, handler func(ws.Event)) {
+	h.Bind(name, ws.HandlerFunc(handler))
+}
+
+// Handler for all webhook events.
+func (h *WebhookHandler) BindAll(handler ws.Handler) {
+	h.Bind("", handler)
+}
+
+func (h *WebhookHandler) UnbindAll(handler ws.Handler) {
+	h.Unbind("", handler)
+}
+
+func (h *WebhookHandler) BindAllFunc(handler func(ws.Event)) {
+	h.Bind("", ws.HandlerFunc(handler))
+}
+
+func (h *WebhookHandler) HandleEvent(e ws.Event) {
+	h.RLock()
+	handlers := make([]ws.Handler, 0, len(h.handlers[e.GetEvent()]) + len(h.handlers[""]))
+	handlers = append(handlers, h.handlers[e.GetEvent()]...)
+	handlers = append(handlers, h.handlers[""]...)
+	h.RUnlock()
+	for _, handler := range handlers {
+		handler.HandleEvent(e)
+	}
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_SIZE))
+	if err != nil {
+		http.Error(w, "Webhook too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	hook, err := h.server.ParseWebhook(r.Header, body)
+	switch err {
+	case nil:
+	case ErrWebhookKey, ErrWebhookSignature:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, e := range hook.Events {
+		h.HandleEvent(e)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		server: s,
+		handlers: make(map[string][]ws.Handler),
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/pusher"
+
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func webhookRequest(body string, key string, secret string) *http.Request {
+	r := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	r.Header.Set("X-Pusher-Key", key)
+	r.Header.Set("X-Pusher-Signature", pusher.Sign(secret, body))
+	return r
+}
+
+func TestWebhookHandler(t *testing.T) {
+	h := NewServer("1", "key", "secret").NewWebhookHandler()
+	var got []string
+	h.BindFunc(MEMBER_ADDED, func(e ws.Event) {
+		got = append(got, "added " + e.(*WebhookEvent).UserId)
+	})
+	h.BindAllFunc(func(e ws.Event) {
+		got = append(got, "all " + e.GetEvent() + " " + e.GetChannel())
+	})
+	body := `{"time_ms":1,"events":[{"name":"member_added","channel":"presence-a","user_id":"u1"},{"name":"client_event","channel":"private-a","event":"client-x","data":"{}","socket_id":"1.1"}]}`
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, webhookRequest(body, "key", "secret"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	expected := []string{"added u1", "all member_added presence-a", "all client_event private-a"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for _, test := range []struct {
+		r     *http.Request
+		code  int
+	}{
+		{webhookRequest(body, "key", "wrong"), http.StatusUnauthorized},
+		{webhookRequest(body, "other", "secret"), http.StatusUnauthorized},
+		{webhookRequest("not json", "key", "secret"), http.StatusBadRequest},
+		{webhookRequest(strings.Repeat("x", MAX_WEBHOOK_SIZE + 1), "key", "secret"), http.StatusRequestEntityTooLarge},
+		{httptest.NewRequest("GET", "/webhook", nil), http.StatusMethodNotAllowed},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, test.r)
+		if w.Code != test.code {
+			t.Fatalf("expected %d, got %d: %s", test.code, w.Code, w.Body)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("rejected webhooks reached the handlers: %v", got)
+	}
+}
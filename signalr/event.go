@@ -0,0 +1,62 @@
+package signalr
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Hub method invoked by the server.  The event and channel are the hub method
+// name.
+type Event struct {
+	Channel    string
+	Target     string
+	Arguments  []interface{}
+	// hub protocol messages, not sent to handlers.
+	handshake     *handshakeResponse
+	msg           *message
+	invocationId  string
+}
+
+func (e *Event) GetEvent() string {
+	return e.Target
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Target = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Channel
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Arguments
+}
+
+func (e *Event) SetData(data interface{}) {
+	switch data := data.(type) {
+	case []interface{}:
+		e.Arguments = data
+	case nil:
+		e.Arguments = nil
+	default:
+		e.Arguments = []interface{}{data}
+	}
+}
+
+func (e *Event) GetDataString() string {
+	// Normalize Arguments as a JSON array.
+	buf, err := json.Marshal(e.Arguments)
+	if err != nil {
+		log.Fatal("JSON Marshaller failed:", err)
+	}
+	return string(buf)
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Arguments = []interface{}{data}
+}
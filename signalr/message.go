@@ -0,0 +1,68 @@
+package signalr
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Record separator that ends every message of the JSON hub protocol.
+const recordSeparator = 0x1e
+
+// Hub message types
+const (
+	typeInvocation = 1
+	typeStreamItem = 2
+	typeCompletion = 3
+	typeStreamInvocation = 4
+	typeCancelInvocation = 5
+	typePing = 6
+	typeClose = 7
+)
+
+type handshakeRequest struct {
+	Protocol  string `json:"protocol"`
+	Version   int `json:"version"`
+}
+
+type handshakeResponse struct {
+	Error  string `json:"error,omitempty"`
+}
+
+// Message sent by the client, arguments are required for invocations.
+type invocation struct {
+	Type          int `json:"type"`
+	InvocationId  string `json:"invocationId,omitempty"`
+	Target        string `json:"target"`
+	Arguments     []interface{} `json:"arguments"`
+}
+
+type message struct {
+	Type            int `json:"type"`
+	InvocationId    string `json:"invocationId,omitempty"`
+	Target          string `json:"target,omitempty"`
+	Arguments       []interface{} `json:"arguments,omitempty"`
+	Item            json.RawMessage `json:"item,omitempty"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	Error           string `json:"error,omitempty"`
+	AllowReconnect  bool `json:"allowReconnect,omitempty"`
+}
+
+// Encode a message as a record.
+func encodeRecord(v interface{}) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, recordSeparator), nil
+}
+
+// Split a frame into records, a frame can hold more then one message.
+func splitRecords(buf []byte) [][]byte {
+	var records [][]byte
+	for _, rec := range bytes.Split(buf, []byte{recordSeparator}) {
+		if len(rec) > 0 {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
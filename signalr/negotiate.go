@@ -0,0 +1,136 @@
+package signalr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const MAX_NEGOTIATE_REDIRECTS = 10
+
+var (
+	ErrNoWebSockets = errors.New("signalr: server doesn't support websockets")
+)
+
+type negotiateResponse struct {
+	ConnectionId         string `json:"connectionId"`
+	ConnectionToken      string `json:"connectionToken"`
+	NegotiateVersion     int `json:"negotiateVersion"`
+	AvailableTransports  []struct {
+		Transport        string `json:"transport"`
+		TransferFormats  []string `json:"transferFormats"`
+	} `json:"availableTransports"`
+	// redirect to another service (e.g. Azure SignalR)
+	Url                  string `json:"url"`
+	AccessToken          string `json:"accessToken"`
+	Error                string `json:"error"`
+}
+
+func (r *negotiateResponse) hasWebSockets() bool {
+	for _, t := range r.AvailableTransports {
+		if t.Transport == "WebSockets" {
+			return true
+		}
+	}
+	return false
+}
+
+func negotiateUrl(hubUrl string) (string, error) {
+	u, err := url.Parse(hubUrl)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/negotiate"
+	q := u.Query()
+	q.Set("negotiateVersion", "1")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Make the websocket url for a negotiated connection.
+func websocketUrl(hubUrl string, id string, accessToken string) (string, error) {
+	u, err := url.Parse(hubUrl)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	q := u.Query()
+	if id != "" {
+		q.Set("id", id)
+	}
+	if accessToken != "" {
+		q.Set("access_token", accessToken)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func negotiateOnce(client *http.Client, hubUrl string, accessToken string) (*negotiateResponse, error) {
+	nu, err := negotiateUrl(hubUrl)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", nu, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer " + accessToken)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signalr: negotiate failed: %s", resp.Status)
+	}
+	res := &negotiateResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, errors.New("signalr: negotiate failed: " + res.Error)
+	}
+	return res, nil
+}
+
+// Negotiate a connection, following redirects.  Returns the websocket url.
+func negotiate(client *http.Client, hubUrl string, accessToken string) (string, error) {
+	for i := 0; i < MAX_NEGOTIATE_REDIRECTS; i++ {
+		res, err := negotiateOnce(client, hubUrl, accessToken)
+		if err != nil {
+			return "", err
+		}
+		if res.Url != "" {
+			hubUrl = res.Url
+			if res.AccessToken != "" {
+				accessToken = res.AccessToken
+			}
+			continue
+		}
+		if !res.hasWebSockets() {
+			return "", ErrNoWebSockets
+		}
+		id := res.ConnectionToken
+		if res.NegotiateVersion < 1 {
+			id = res.ConnectionId
+		}
+		return websocketUrl(hubUrl, id, accessToken)
+	}
+	return "", errors.New("signalr: too many negotiate redirects")
+}
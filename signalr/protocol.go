@@ -0,0 +1,291 @@
+package signalr
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Codec for the JSON hub protocol, also tracks the invocations waiting for a
+// completion.
+type signalrProtocol struct {
+	sync.Mutex
+	client        *ws.ProtocolClient
+	cf            SignalRConfig
+	ready         bool
+	nextId        uint64
+	pending       map[string]completionFn
+	lastReceived  time.Time
+}
+
+func (p *signalrProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *signalrProtocol) isReady() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.ready
+}
+
+func (p *signalrProtocol) makeId() string {
+	p.Lock()
+	defer p.Unlock()
+	p.nextId++
+	return strconv.FormatUint(p.nextId, 10)
+}
+
+func (p *signalrProtocol) register(id string, fn completionFn) {
+	p.Lock()
+	defer p.Unlock()
+	p.pending[id] = fn
+}
+
+func (p *signalrProtocol) forget(id string) {
+	p.Lock()
+	defer p.Unlock()
+	delete(p.pending, id)
+}
+
+func (p *signalrProtocol) sendRecord(v interface{}) {
+	buf, err := encodeRecord(v)
+	if err != nil {
+		log.Println("Error sending message:", err)
+		return
+	}
+	p.client.SendMessage(buf)
+}
+
+// Send an invocation, fn is registered for the completion if there is an id.
+func (p *signalrProtocol) invoke(msgType int, id string, target string, args []interface{}, fn completionFn) error {
+	if !p.isReady() {
+		return ErrNotConnected
+	}
+	if id != "" {
+		p.register(id, fn)
+	}
+	if args == nil {
+		args = []interface{}{}
+	}
+	p.sendRecord(&invocation{
+		Type: msgType,
+		InvocationId: id,
+		Target: target,
+		Arguments: args,
+	})
+	return nil
+}
+
+func (p *signalrProtocol) accessToken() string {
+	if p.cf.AccessTokenFactory == nil {
+		return ""
+	}
+	token, err := p.cf.AccessTokenFactory()
+	if err != nil {
+		log.Println("SignalR access token failed:", err)
+	}
+	return token
+}
+
+// Negotiate a new connection for each connect attempt.
+func (p *signalrProtocol) ConnectUrl(u string) string {
+	token := p.accessToken()
+	var err error
+	var wsUrl string
+	if p.cf.SkipNegotiation {
+		wsUrl, err = websocketUrl(u, "", token)
+	} else {
+		wsUrl, err = negotiate(p.cf.HttpClient, u, token)
+	}
+	if err != nil {
+		log.Println("SignalR negotiate failed:", err)
+		return u
+	}
+	return wsUrl
+}
+
+func (p *signalrProtocol) Opened() bool {
+	// wait for the handshake response.
+	return false
+}
+
+func (p *signalrProtocol) Closed() {
+	p.Lock()
+	p.ready = false
+	pending := p.pending
+	p.pending = make(map[string]completionFn)
+	p.Unlock()
+	// fail invocations & streams waiting for a completion.
+	for id, fn := range pending {
+		p.client.SafeCall(id, "completion", func() {
+			fn(nil)
+		})
+	}
+}
+
+func (p *signalrProtocol) HandshakeFrame() []byte {
+	buf, _ := encodeRecord(&handshakeRequest{
+		Protocol: "json",
+		Version: 1,
+	})
+	return buf
+}
+
+// Events invoke the hub method named by the event, without waiting for it.
+func (p *signalrProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	if !p.isReady() {
+		return nil, ErrNotConnected
+	}
+	var args []interface{}
+	if data, ok := e.GetData().([]interface{}); ok {
+		args = data
+	} else if e.GetData() != nil {
+		args = []interface{}{e.GetData()}
+	} else {
+		args = []interface{}{}
+	}
+	return encodeRecord(&invocation{
+		Type: typeInvocation,
+		Target: e.GetEvent(),
+		Arguments: args,
+	})
+}
+
+func (p *signalrProtocol) DecodeEvent(buf []byte) (ws.Event, error) {
+	events, err := p.DecodeEvents(buf)
+	if len(events) == 0 {
+		return nil, err
+	}
+	return events[0], err
+}
+
+func (p *signalrProtocol) DecodeEvents(buf []byte) ([]ws.Event, error) {
+	p.Lock()
+	p.lastReceived = time.Now()
+	ready := p.ready
+	p.Unlock()
+	var events []ws.Event
+	for _, record := range splitRecords(buf) {
+		if !ready {
+			// first record is the handshake response.
+			res := &handshakeResponse{}
+			if err := json.Unmarshal(record, res); err != nil {
+				return events, ws.NewError("Bad handshake: " + err.Error(), false, true, 0)
+			}
+			events = append(events, &Event{handshake: res})
+			ready = true
+			continue
+		}
+		msg := &message{}
+		if err := json.Unmarshal(record, msg); err != nil {
+			// skip bad records.
+			log.Println("SignalR bad record:", err)
+			continue
+		}
+		if msg.Type == typeInvocation {
+			events = append(events, &Event{
+				Channel: msg.Target,
+				Target: msg.Target,
+				Arguments: msg.Arguments,
+				invocationId: msg.InvocationId,
+			})
+		} else {
+			events = append(events, &Event{msg: msg})
+		}
+	}
+	return events, nil
+}
+
+func (p *signalrProtocol) handleHandshake(res *handshakeResponse) error {
+	if res.Error != "" {
+		return ws.NewError("Handshake failed: " + res.Error, false, false, 0)
+	}
+	p.Lock()
+	p.ready = true
+	p.Unlock()
+	// connect timeout is done, start heartbeats.
+	p.client.Connected()
+	return nil
+}
+
+func (p *signalrProtocol) handleCompletion(msg *message) {
+	p.Lock()
+	fn := p.pending[msg.InvocationId]
+	if msg.Type == typeCompletion {
+		delete(p.pending, msg.InvocationId)
+	}
+	p.Unlock()
+	if fn != nil {
+		p.client.SafeCall(msg.InvocationId, "completion", func() {
+			fn(msg)
+		})
+	}
+}
+
+func (p *signalrProtocol) handleMessage(msg *message) error {
+	switch msg.Type {
+	case typeStreamItem, typeCompletion:
+		p.handleCompletion(msg)
+	case typePing:
+	case typeClose:
+		log.Println("SignalR close:", msg.Error)
+		if msg.AllowReconnect {
+			return ws.ErrDelayReconnect
+		}
+		return ws.NewError("Close: " + msg.Error, false, false, 0)
+	}
+	return nil
+}
+
+func (p *signalrProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	event, ok := e.(*Event)
+	if !ok {
+		return ws.KindEvent, nil
+	}
+	switch {
+	case event.handshake != nil:
+		return ws.KindInternal, p.handleHandshake(event.handshake)
+	case event.msg != nil:
+		return ws.KindInternal, p.handleMessage(event.msg)
+	case event.invocationId != "":
+		// client results are not supported.
+		p.sendRecord(&message{
+			Type: typeCompletion,
+			InvocationId: event.invocationId,
+			Error: "Client doesn't support results.",
+		})
+	}
+	return ws.KindEvent, nil
+}
+
+// hub methods are pushed by the server, nothing to subscribe.
+func (p *signalrProtocol) SubscribeFrame(method string) []byte {
+	return nil
+}
+
+func (p *signalrProtocol) UnsubscribeFrame(method string) []byte {
+	return nil
+}
+
+func (p *signalrProtocol) SendPing() {
+	p.sendRecord(&message{Type: typePing})
+	// the server pings us too, it is alive if we heard from it recently.
+	p.Lock()
+	alive := time.Since(p.lastReceived) < p.cf.ServerTimeout
+	p.Unlock()
+	if alive {
+		p.client.Socket().HandlePong()
+	}
+}
+
+func (p *signalrProtocol) PingFrame() []byte {
+	return nil
+}
+
+func (p *signalrProtocol) PongFrame() []byte {
+	return nil
+}
@@ -0,0 +1,157 @@
+package signalr
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrNotConnected = errors.New("signalr: handshake not completed")
+	ErrDisconnected = errors.New("signalr: disconnected before completion")
+)
+
+// Called with the stream item or completion for an invocation, nil on
+// disconnect.
+type completionFn func(msg *message)
+
+// Error returned by the hub method.
+type HubError struct {
+	Message  string
+}
+
+func (e *HubError) Error() string {
+	return "signalr: " + e.Message
+}
+
+type SignalRClient struct {
+	*ws.ProtocolClient
+	cf      SignalRConfig
+	proto   *signalrProtocol
+}
+
+// Invoke a hub method and wait for the result.
+func (c *SignalRClient) Invoke(ctx context.Context, target string, args ...interface{}) (json.RawMessage, error) {
+	type result struct {
+		res json.RawMessage
+		err error
+	}
+	done := make(chan result, 1)
+	id := c.proto.makeId()
+	err := c.proto.invoke(typeInvocation, id, target, args, func(msg *message) {
+		switch {
+		case msg == nil:
+			done <- result{err: ErrDisconnected}
+		case msg.Error != "":
+			done <- result{err: &HubError{Message: msg.Error}}
+		default:
+			done <- result{res: msg.Result}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-ctx.Done():
+		c.proto.forget(id)
+		return nil, ctx.Err()
+	}
+}
+
+// Invoke a hub method without waiting for it to finish.
+func (c *SignalRClient) Send(target string, args ...interface{}) error {
+	return c.proto.invoke(typeInvocation, "", target, args, nil)
+}
+
+// Start a server to client stream.  Use the Stream's Id to cancel it.
+func (c *SignalRClient) StreamInvocation(target string, args ...interface{}) (*Stream, error) {
+	p := c.proto
+	if !p.isReady() {
+		return nil, ErrNotConnected
+	}
+	stream := NewStream(p.makeId(), target, c)
+	c.AddChannel(stream.Id, stream)
+	err := p.invoke(typeStreamInvocation, stream.Id, target, args, func(msg *message) {
+		event := &Event{
+			Channel: stream.Id,
+			Target: "complete",
+		}
+		switch {
+		case msg == nil:
+			event.Target = "error"
+			event.Arguments = []interface{}{ErrDisconnected.Error()}
+		case msg.Type == typeStreamItem:
+			var item interface{}
+			json.Unmarshal(msg.Item, &item)
+			event.Target = "item"
+			event.Arguments = []interface{}{item}
+		case msg.Error != "":
+			event.Target = "error"
+			event.Arguments = []interface{}{msg.Error}
+		}
+		c.Dispatch(event)
+	})
+	if err != nil {
+		stream.finish()
+		c.Unsubscribe(stream.Id)
+		return nil, err
+	}
+	return stream, nil
+}
+
+type SignalRConfig struct {
+	ws.Config
+	// Connect to the websocket url directly (requires a websocket only server).
+	SkipNegotiation     bool
+	AccessTokenFactory  func() (string, error)
+	// Disconnect if nothing was received from the server in this time.
+	ServerTimeout       time.Duration
+	HttpClient          *http.Client
+}
+
+var (
+	// Server and client ping each other every 15 seconds.
+	DefaultSignalR = SignalRConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 15,
+			ActivityTimeout: time.Second * 15,
+			PingTimeout:     time.Second * 15,
+			ClientHeartbeat: true,
+		},
+		ServerTimeout:   time.Second * 30,
+		HttpClient:      http.DefaultClient,
+	}
+)
+
+func (cf SignalRConfig) NewSignalRUrl(hubUrl string) (*SignalRClient, error) {
+	u, err := url.Parse(hubUrl)
+	if err != nil {
+		return nil, err
+	}
+	if cf.HttpClient == nil {
+		cf.HttpClient = http.DefaultClient
+	}
+	if cf.ServerTimeout <= 0 {
+		cf.ServerTimeout = DefaultSignalR.ServerTimeout
+	}
+	proto := &signalrProtocol{
+		cf: cf,
+		pending: make(map[string]completionFn),
+	}
+	return &SignalRClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		cf: cf,
+		proto: proto,
+	}, nil
+}
+
+func NewSignalRUrl(hubUrl string) (*SignalRClient, error) {
+	return DefaultSignalR.NewSignalRUrl(hubUrl)
+}
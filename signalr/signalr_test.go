@@ -0,0 +1,193 @@
+package signalr
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Hub server with a negotiate endpoint, the websocket handler gets the
+// connection after the handshake.
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hub/negotiate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Query().Get("negotiateVersion") != "1" {
+			t.Errorf("bad negotiate request: %s %s", r.Method, r.URL)
+		}
+		w.Write([]byte(`{"connectionId":"c1","connectionToken":"tok1","negotiateVersion":1,
+			"availableTransports":[{"transport":"WebSockets","transferFormats":["Text"]}]}`))
+	})
+	mux.Handle("/hub", wstest.Handler(t, func(conn *websocket.Conn, r *http.Request) {
+		if r.URL.Query().Get("id") != "tok1" {
+			t.Errorf("expected connection token, got %s", r.URL)
+		}
+		var req handshakeRequest
+		if rec := readRecord(t, conn); rec == nil || json.Unmarshal(rec, &req) != nil || req.Protocol != "json" {
+			t.Errorf("bad handshake: %s", rec)
+			return
+		}
+		writeRecords(conn, &handshakeResponse{})
+		handler(conn)
+	}))
+	// negotiate is plain http.
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/hub"
+}
+
+func readRecord(t *testing.T, conn *websocket.Conn) []byte {
+	_, buf, err := conn.ReadMessage()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	records := splitRecords(buf)
+	if len(records) != 1 {
+		t.Errorf("expected one record, got %q", buf)
+		return nil
+	}
+	return records[0]
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) *message {
+	msg := &message{}
+	rec := readRecord(t, conn)
+	if rec == nil || json.Unmarshal(rec, msg) != nil {
+		return nil
+	}
+	return msg
+}
+
+// Write records in one frame.
+func writeRecords(conn *websocket.Conn, msgs ...interface{}) {
+	var buf []byte
+	for _, m := range msgs {
+		rec, _ := encodeRecord(m)
+		buf = append(buf, rec...)
+	}
+	conn.WriteMessage(websocket.TextMessage, buf)
+}
+
+func TestNegotiate(t *testing.T) {
+	var srvUrl string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hub/negotiate", func(w http.ResponseWriter, r *http.Request) {
+		// redirect to another service with a new token.
+		w.Write([]byte(`{"url":"` + srvUrl + `/svc","accessToken":"svc-token"}`))
+	})
+	mux.HandleFunc("/svc/negotiate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer svc-token" {
+			t.Errorf("expected redirect token, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"connectionId":"c2","negotiateVersion":0,
+			"availableTransports":[{"transport":"WebSockets"}]}`))
+	})
+	mux.HandleFunc("/nows/negotiate", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"connectionId":"c3","availableTransports":[{"transport":"LongPolling"}]}`))
+	})
+	mux.HandleFunc("/fail/negotiate", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"no hub"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	srvUrl = srv.URL
+	u, err := negotiate(http.DefaultClient, srv.URL + "/hub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ws" + strings.TrimPrefix(srv.URL, "http") + "/svc?access_token=svc-token&id=c2"
+	if u != expected {
+		t.Fatalf("expected %s, got %s", expected, u)
+	}
+	if _, err := negotiate(http.DefaultClient, srv.URL + "/nows", ""); err != ErrNoWebSockets {
+		t.Fatalf("expected ErrNoWebSockets, got %v", err)
+	}
+	if _, err := negotiate(http.DefaultClient, srv.URL + "/fail", ""); err == nil || !strings.Contains(err.Error(), "no hub") {
+		t.Fatalf("expected negotiate error, got %v", err)
+	}
+	if _, err := negotiate(http.DefaultClient, srv.URL + "/missing", ""); err == nil {
+		t.Fatal("expected error for missing hub")
+	}
+}
+
+func TestInvokeAndEvents(t *testing.T) {
+	done := make(chan struct{})
+	bound := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		// bad records are skipped.
+		conn.WriteMessage(websocket.TextMessage, []byte("not json\x1e"))
+		writeRecords(conn,
+			&message{Type: typePing},
+			&message{Type: typeInvocation, Target: "news", Arguments: []interface{}{"hi"}})
+		inv := readMessage(t, conn)
+		if inv == nil || inv.Type != typeInvocation || inv.Target != "add" || inv.InvocationId == "" {
+			t.Errorf("expected invocation, got %+v", inv)
+			return
+		}
+		writeRecords(conn, &message{Type: typeCompletion, InvocationId: inv.InvocationId, Result: json.RawMessage(`3`)})
+		stream := readMessage(t, conn)
+		if stream == nil || stream.Type != typeStreamInvocation || stream.Target != "count" {
+			t.Errorf("expected stream invocation, got %+v", stream)
+			return
+		}
+		<-bound
+		writeRecords(conn,
+			&message{Type: typeStreamItem, InvocationId: stream.InvocationId, Item: json.RawMessage(`1`)},
+			&message{Type: typeStreamItem, InvocationId: stream.InvocationId, Item: json.RawMessage(`2`)},
+			&message{Type: typeCompletion, InvocationId: stream.InvocationId})
+		<-done
+	})
+	defer close(done)
+	client, err := NewSignalRUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 4)
+	client.Subscribe("news").BindAllFunc(func(e ws.Event) {
+		got <- e
+	})
+	select {
+	case e := <-got:
+		if e.GetEvent() != "news" || e.GetDataString() != `["hi"]` {
+			t.Fatalf("bad event: %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for invocation")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	res, err := client.Invoke(ctx, "add", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "3" {
+		t.Fatalf("expected 3, got %s", res)
+	}
+	stream, err := client.StreamInvocation("count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.BindAllFunc(func(e ws.Event) {
+		got <- e
+	})
+	close(bound)
+	for _, expected := range []string{"item:[1]", "item:[2]", "complete:null"} {
+		select {
+		case e := <-got:
+			if e.GetEvent() + ":" + e.GetDataString() != expected {
+				t.Fatalf("expected %s, got %s:%s", expected, e.GetEvent(), e.GetDataString())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for", expected)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package signalr
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"sync"
+)
+
+// Server to client stream.  Items are delivered to the handlers bound to the
+// "item" event, the stream ends with a "complete" or "error" event.
+type Stream struct {
+	*ws.PublicChannel
+	proto      *signalrProtocol
+	Id         string
+	Target     string
+	mu         sync.Mutex
+	done       bool
+}
+
+func (s *Stream) finish() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := s.done
+	s.done = true
+	return !done
+}
+
+func (s *Stream) HandleEvent(event ws.Event) {
+	switch event.GetEvent() {
+	case "error", "complete":
+		s.finish()
+		s.SetActive(false)
+		defer s.proto.client.Unsubscribe(s.Id)
+	}
+	s.PublicChannel.HandleEvent(event)
+}
+
+func (s *Stream) UpdateClientState(connected bool) {
+	// streams are not restarted after a reconnect.
+	if !connected {
+		s.SetActive(false)
+	}
+}
+
+func (s *Stream) Subscribe() {
+}
+
+// Cancel the stream.
+func (s *Stream) Unsubscribe() {
+	if !s.finish() {
+		return
+	}
+	s.proto.forget(s.Id)
+	s.proto.sendRecord(&message{
+		Type: typeCancelInvocation,
+		InvocationId: s.Id,
+	})
+	s.SetActive(false)
+}
+
+func newStream(id string, target string, proto *signalrProtocol) *Stream {
+	s := &Stream{
+		PublicChannel: ws.NewPublicChannel(id, proto.client),
+		proto: proto,
+		Id: id,
+		Target: target,
+	}
+	s.SetActive(true)
+	return s
+}
+
+func NewStream(id string, target string, client *SignalRClient) *Stream {
+	return newStream(id, target, client.proto)
+}
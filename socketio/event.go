@@ -0,0 +1,79 @@
+package socketio
+
+import (
+	"encoding/json"
+	"log"
+)
+
+type Event struct {
+	Namespace  string
+	Event      string
+	Args       []interface{}
+	ackId      int
+	proto      *socketioProtocol
+	open       bool
+}
+
+func (e *Event) GetEvent() string {
+	return e.Event
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Event = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Namespace
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Namespace = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Args
+}
+
+func (e *Event) SetData(data interface{}) {
+	if args, ok := data.([]interface{}); ok {
+		e.Args = args
+	} else {
+		e.Args = []interface{}{data}
+	}
+}
+
+func (e *Event) GetDataString() string {
+	// Single string argument is returned as is.
+	if len(e.Args) == 1 {
+		if s, ok := e.Args[0].(string); ok {
+			return s
+		}
+	}
+	buf, err := json.Marshal(e.Args)
+	if err != nil {
+		log.Fatal("JSON Marshaller failed:", err)
+	}
+	return string(buf)
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Args = []interface{}{data}
+}
+
+// Server wants an acknowledgement for this event.
+func (e *Event) WantsAck() bool {
+	return e.ackId >= 0 && e.proto != nil
+}
+
+// Send acknowledgement for this event.
+func (e *Event) Ack(args ...interface{}) {
+	if !e.WantsAck() {
+		return
+	}
+	e.proto.sendPacket(&packet{
+		typ: packetAck,
+		namespace: e.Namespace,
+		id: e.ackId,
+		data: args,
+	})
+}
@@ -0,0 +1,46 @@
+package socketio
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+)
+
+type Namespace struct {
+	*ws.PublicChannel
+	client     *ws.ProtocolClient
+	proto      *socketioProtocol
+	name       string
+	auth       interface{}
+}
+
+func (n *Namespace) UpdateClientState(connected bool) {
+	if connected {
+		// Engine.IO connection opened, connect to the namespace.
+		n.Subscribe()
+	} else {
+		n.SetActive(false)
+	}
+}
+
+func (n *Namespace) Emit(event string, args ...interface{}) {
+	emit(n.client, n.name, event, -1, args)
+}
+
+func (n *Namespace) EmitWithAck(event string, args ...interface{}) *Ack {
+	ack := n.proto.newAck()
+	emit(n.client, n.name, event, ack.Id, args)
+	return ack
+}
+
+func newNamespace(name string, auth interface{}, proto *socketioProtocol) *Namespace {
+	return &Namespace{
+		PublicChannel: ws.NewPublicChannel(name, proto.client),
+		client: proto.client,
+		proto: proto,
+		name: name,
+		auth: auth,
+	}
+}
+
+func NewNamespace(name string, auth interface{}, client *SocketIOClient) *Namespace {
+	return newNamespace(name, auth, client.proto)
+}
@@ -0,0 +1,166 @@
+package socketio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Socket.IO packet types
+const (
+	packetConnect = iota
+	packetDisconnect
+	packetEvent
+	packetAck
+	packetConnectError
+	packetBinaryEvent
+	packetBinaryAck
+)
+
+type packet struct {
+	typ          int
+	namespace    string
+	id           int   // ack id, -1 if no ack
+	data         interface{}
+	attachments  int
+	buffers      [][]byte
+}
+
+// replace binary values with placeholders.
+func deconstruct(v interface{}, buffers *[][]byte) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		placeholder := map[string]interface{}{
+			"_placeholder": true,
+			"num": len(*buffers),
+		}
+		*buffers = append(*buffers, v)
+		return placeholder
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, e := range v {
+			res[i] = deconstruct(e, buffers)
+		}
+		return res
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			res[k] = deconstruct(e, buffers)
+		}
+		return res
+	}
+	return v
+}
+
+// replace placeholders with binary values.
+func reconstruct(v interface{}, buffers [][]byte) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i, e := range v {
+			v[i] = reconstruct(e, buffers)
+		}
+	case map[string]interface{}:
+		if placeholder, _ := v["_placeholder"].(bool); placeholder {
+			num, ok := v["num"].(float64)
+			if ok && int(num) >= 0 && int(num) < len(buffers) {
+				return buffers[int(num)]
+			}
+			return nil
+		}
+		for k, e := range v {
+			v[k] = reconstruct(e, buffers)
+		}
+	}
+	return v
+}
+
+// Encode packet, binary attachments are returned separately.
+func (p *packet) encode() (string, [][]byte, error) {
+	var buffers [][]byte
+	data := deconstruct(p.data, &buffers)
+	typ := p.typ
+	if len(buffers) > 0 {
+		switch typ {
+		case packetEvent:
+			typ = packetBinaryEvent
+		case packetAck:
+			typ = packetBinaryAck
+		}
+	}
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(typ))
+	if typ == packetBinaryEvent || typ == packetBinaryAck {
+		b.WriteString(strconv.Itoa(len(buffers)))
+		b.WriteByte('-')
+	}
+	if p.namespace != "" && p.namespace != "/" {
+		b.WriteString(p.namespace)
+		b.WriteByte(',')
+	}
+	if p.id >= 0 {
+		b.WriteString(strconv.Itoa(p.id))
+	}
+	if data != nil {
+		buf, err := json.Marshal(data)
+		if err != nil {
+			return "", nil, err
+		}
+		b.Write(buf)
+	}
+	return b.String(), buffers, nil
+}
+
+func decodePacket(msg string) (*packet, error) {
+	if len(msg) == 0 {
+		return nil, fmt.Errorf("socketio: empty packet")
+	}
+	p := &packet{
+		typ: int(msg[0] - '0'),
+		namespace: "/",
+		id: -1,
+	}
+	if p.typ < packetConnect || p.typ > packetBinaryAck {
+		return nil, fmt.Errorf("socketio: bad packet type: %q", msg[0])
+	}
+	msg = msg[1:]
+	// attachment count
+	if p.typ == packetBinaryEvent || p.typ == packetBinaryAck {
+		i := strings.IndexByte(msg, '-')
+		if i < 0 {
+			return nil, fmt.Errorf("socketio: missing attachment count")
+		}
+		n, err := strconv.Atoi(msg[:i])
+		if err != nil {
+			return nil, err
+		}
+		p.attachments = n
+		msg = msg[i+1:]
+	}
+	// namespace
+	if len(msg) > 0 && msg[0] == '/' {
+		i := strings.IndexByte(msg, ',')
+		if i < 0 {
+			p.namespace = msg
+			msg = ""
+		} else {
+			p.namespace = msg[:i]
+			msg = msg[i+1:]
+		}
+	}
+	// ack id
+	i := 0
+	for i < len(msg) && msg[i] >= '0' && msg[i] <= '9' {
+		i++
+	}
+	if i > 0 {
+		p.id, _ = strconv.Atoi(msg[:i])
+		msg = msg[i:]
+	}
+	if len(msg) > 0 {
+		if err := json.Unmarshal([]byte(msg), &p.data); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
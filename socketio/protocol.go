@@ -0,0 +1,295 @@
+package socketio
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Codec for Socket.IO v5 over Engine.IO v4.
+type socketioProtocol struct {
+	client        *ws.ProtocolClient
+	mu            sync.Mutex
+	ackId         int
+	acks          map[int]*Ack
+	binary        *packet  // waiting for binary attachments
+	pingInterval  time.Duration
+}
+
+func (p *socketioProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *socketioProtocol) forget(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.acks, id)
+}
+
+func (p *socketioProtocol) Opened() bool {
+	// wait for the Engine.IO open packet.
+	return false
+}
+
+func (p *socketioProtocol) Closed() {
+	// fail all emits waiting for an ack.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, ack := range p.acks {
+		close(ack.args)
+		delete(p.acks, id)
+	}
+	p.binary = nil
+}
+
+func (p *socketioProtocol) HandshakeFrame() []byte {
+	return nil
+}
+
+func (p *socketioProtocol) handleOpen(data string) (ws.Event, error) {
+	var msg struct {
+		Sid           string `json:"sid"`
+		PingInterval  int `json:"pingInterval"`
+		PingTimeout   int `json:"pingTimeout"`
+	}
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return nil, err
+	}
+	// The server sends pings every pingInterval, the connection is dead if
+	// none shows up within pingInterval + pingTimeout.
+	p.client.Socket().SetPingTimeout(time.Duration(msg.PingTimeout) * time.Millisecond)
+	p.pingInterval = time.Duration(msg.PingInterval + msg.PingTimeout) * time.Millisecond
+	return &Event{
+		Event: "open",
+		ackId: -1,
+		open: true,
+	}, nil
+}
+
+func (p *socketioProtocol) ActivityTimeout(e ws.Event) time.Duration {
+	return p.pingInterval
+}
+
+func (p *socketioProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	if len(msg) == 0 {
+		return nil, nil
+	}
+	// handle Engine.IO packets.
+	switch msg[0] {
+	case '0':
+		return p.handleOpen(string(msg[1:]))
+	case '1':
+		log.Println("Engine.IO close.")
+		return nil, ws.ErrReconnect
+	case '2':
+		// a server ping is the heartbeat, reset the timeout.
+		p.client.Socket().HandlePong()
+		p.client.SendMessage(append([]byte{'3'}, msg[1:]...))
+	case '3':
+		p.client.Socket().HandlePong()
+	case '4':
+		return p.handlePacket(string(msg[1:]))
+	}
+	return nil, nil
+}
+
+func (p *socketioProtocol) DecodeBinary(msg []byte) ([]ws.Event, error) {
+	pkt := p.binary
+	if pkt == nil {
+		log.Println("Unexpected binary message.")
+		return nil, nil
+	}
+	pkt.buffers = append(pkt.buffers, msg)
+	if len(pkt.buffers) < pkt.attachments {
+		return nil, nil
+	}
+	p.binary = nil
+	pkt.data = reconstruct(pkt.data, pkt.buffers)
+	if e := p.event(pkt); e != nil {
+		return []ws.Event{e}, nil
+	}
+	return nil, nil
+}
+
+func (p *socketioProtocol) BinaryFrames() bool {
+	return false
+}
+
+func (p *socketioProtocol) handlePacket(msg string) (ws.Event, error) {
+	pkt, err := decodePacket(msg)
+	if err != nil {
+		return nil, err
+	}
+	if pkt.attachments > 0 {
+		// wait for binary attachments.
+		p.binary = pkt
+		return nil, nil
+	}
+	if e := p.event(pkt); e != nil {
+		return e, nil
+	}
+	return nil, nil
+}
+
+func (p *socketioProtocol) event(pkt *packet) *Event {
+	event := &Event{
+		Namespace: pkt.namespace,
+		ackId: -1,
+		proto: p,
+	}
+	switch pkt.typ {
+	case packetConnect:
+		event.Event = "connect"
+		event.SetData(pkt.data)
+	case packetDisconnect:
+		event.Event = "disconnect"
+	case packetConnectError:
+		log.Println("Socket.IO connect error:", pkt.namespace, pkt.data)
+		event.Event = "connect_error"
+		event.SetData(pkt.data)
+	case packetEvent, packetBinaryEvent:
+		args, _ := pkt.data.([]interface{})
+		if len(args) == 0 {
+			log.Println("Socket.IO event without name:", pkt.namespace)
+			return nil
+		}
+		event.Event, _ = args[0].(string)
+		event.Args = args[1:]
+		event.ackId = pkt.id
+	case packetAck, packetBinaryAck:
+		args, _ := pkt.data.([]interface{})
+		p.mu.Lock()
+		ack := p.acks[pkt.id]
+		delete(p.acks, pkt.id)
+		p.mu.Unlock()
+		if ack != nil {
+			ack.args <- args
+		}
+		return nil
+	}
+	return event
+}
+
+func (p *socketioProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	if e, ok := e.(*Event); ok && e.open {
+		return ws.KindConnected, nil
+	}
+	switch e.GetEvent() {
+	case "connect":
+		return ws.KindSubscribed, nil
+	case "disconnect", "connect_error":
+		return ws.KindUnsubscribed, nil
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *socketioProtocol) eventPacket(e ws.Event) *packet {
+	var args []interface{}
+	switch data := e.GetData().(type) {
+	case nil:
+	case []interface{}:
+		args = data
+	default:
+		args = []interface{}{data}
+	}
+	id := -1
+	if e, ok := e.(*Event); ok {
+		id = e.ackId
+	}
+	return &packet{
+		typ: packetEvent,
+		namespace: e.GetChannel(),
+		id: id,
+		data: append([]interface{}{e.GetEvent()}, args...),
+	}
+}
+
+func (p *socketioProtocol) EncodeAttachments(e ws.Event) ([]byte, [][]byte, error) {
+	msg, buffers, err := p.eventPacket(e).encode()
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte("4" + msg), buffers, nil
+}
+
+func (p *socketioProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	buf, _, err := p.EncodeAttachments(e)
+	return buf, err
+}
+
+func (p *socketioProtocol) sendPacket(pkt *packet) {
+	msg, buffers, err := pkt.encode()
+	if err != nil {
+		log.Println("Error sending packet:", err)
+		return
+	}
+	p.client.SendMessage([]byte("4" + msg))
+	for _, buf := range buffers {
+		p.client.SendBinaryMessage(buf)
+	}
+}
+
+func (p *socketioProtocol) namespace(name string) *Namespace {
+	ns, _ := p.client.FindChannel(name).(*Namespace)
+	return ns
+}
+
+func (p *socketioProtocol) SubscribeFrame(namespace string) []byte {
+	var auth interface{}
+	if ns := p.namespace(namespace); ns != nil {
+		auth = ns.auth
+	}
+	msg, _, err := (&packet{
+		typ: packetConnect,
+		namespace: namespace,
+		id: -1,
+		data: auth,
+	}).encode()
+	if err != nil {
+		log.Println("Bad namespace auth:", namespace, err)
+		return nil
+	}
+	return []byte("4" + msg)
+}
+
+func (p *socketioProtocol) UnsubscribeFrame(namespace string) []byte {
+	msg, _, _ := (&packet{
+		typ: packetDisconnect,
+		namespace: namespace,
+		id: -1,
+	}).encode()
+	return []byte("4" + msg)
+}
+
+// Engine.IO v4 servers send the pings, nothing to do but wait for the ping
+// timeout.
+func (p *socketioProtocol) SendPing() {
+}
+
+func (p *socketioProtocol) PingFrame() []byte {
+	return nil
+}
+
+func (p *socketioProtocol) PongFrame() []byte {
+	return nil
+}
+
+func (p *socketioProtocol) NewChannel(namespace string) ws.Channel {
+	return newNamespace(namespace, nil, p)
+}
+
+func (p *socketioProtocol) newAck() *Ack {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ack := &Ack{
+		Id: p.ackId,
+		proto: p,
+		args: make(chan []interface{}, 1),
+	}
+	p.ackId++
+	p.acks[ack.Id] = ack
+	return ack
+}
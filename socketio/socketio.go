@@ -0,0 +1,153 @@
+package socketio
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrDisconnected = errors.New("socketio: disconnected before ack")
+)
+
+// Acknowledgement future for an emitted event.
+type Ack struct {
+	Id      int
+	proto   *socketioProtocol
+	args    chan []interface{}
+}
+
+// Wait for the acknowledgement arguments.
+func (a *Ack) Wait(ctx context.Context) ([]interface{}, error) {
+	select {
+	case args, ok := <-a.args:
+		if !ok {
+			return nil, ErrDisconnected
+		}
+		return args, nil
+	case <-ctx.Done():
+		a.proto.forget(a.Id)
+		return nil, ctx.Err()
+	}
+}
+
+type SocketIOClient struct {
+	*ws.ProtocolClient
+	proto  *socketioProtocol
+}
+
+func emit(c *ws.ProtocolClient, namespace string, event string, ackId int, args []interface{}) {
+	c.SendEvent(&Event{
+		Namespace: namespace,
+		Event: event,
+		Args: args,
+		ackId: ackId,
+	})
+}
+
+// Emit an event to a namespace.
+func (c *SocketIOClient) Emit(namespace string, event string, args ...interface{}) {
+	emit(c.ProtocolClient, namespace, event, -1, args)
+}
+
+// Emit an event to a namespace, the acknowledgement can be waited for on the
+// returned Ack.
+func (c *SocketIOClient) EmitWithAck(namespace string, event string, args ...interface{}) *Ack {
+	ack := c.proto.newAck()
+	emit(c.ProtocolClient, namespace, event, ack.Id, args)
+	return ack
+}
+
+// Connect to a namespace with an auth payload.
+func (c *SocketIOClient) Of(namespace string, auth interface{}) *Namespace {
+	if namespace == "" {
+		namespace = "/"
+	}
+	if ns, ok := c.FindChannel(namespace).(*Namespace); ok {
+		return ns
+	}
+	// create a new namespace.
+	ns := NewNamespace(namespace, auth, c)
+	c.AddChannel(namespace, ns)
+	return ns
+}
+
+func (c *SocketIOClient) Subscribe(namespace string) ws.Channel {
+	return c.Of(namespace, nil)
+}
+
+type SocketIOConfig struct {
+	ws.Config
+	Path              string
+}
+
+var (
+	DefaultSocketIO = SocketIOConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 120,
+			PingTimeout:     time.Second * 30,
+		},
+		Path:            "/socket.io/",
+	}
+)
+
+func (cf SocketIOConfig) engineUrl(serverUrl string) (*url.URL, error) {
+	u, err := url.Parse(serverUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = cf.Path
+	}
+	query := u.Query()
+	query.Set("EIO", "4")
+	query.Set("transport", "websocket")
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+func (cf SocketIOConfig) endpointUrls(urls []string) []string {
+	res := make([]string, 0, len(urls))
+	for _, rawUrl := range urls {
+		u, err := cf.engineUrl(rawUrl)
+		if err != nil {
+			log.Println("Bad endpoint url:", rawUrl, err)
+			continue
+		}
+		res = append(res, u.String())
+	}
+	return res
+}
+
+// Connect to a Socket.IO server (e.g. "wss://host").  Use Of() or Subscribe()
+// to connect to namespaces, including the main "/" namespace.
+func (cf SocketIOConfig) NewSocketIOUrl(serverUrl string) (*SocketIOClient, error) {
+	u, err := cf.engineUrl(serverUrl)
+	if err != nil {
+		return nil, err
+	}
+	// fallback endpoints need the same params.
+	cf.FallbackUrls = cf.endpointUrls(cf.FallbackUrls)
+	if resolver := cf.EndpointResolver; resolver != nil {
+		cf.EndpointResolver = func() ([]string, error) {
+			urls, err := resolver()
+			return cf.endpointUrls(urls), err
+		}
+	}
+	proto := &socketioProtocol{
+		acks: make(map[int]*Ack),
+	}
+	return &SocketIOClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		proto: proto,
+	}, nil
+}
+
+func NewSocketIOUrl(serverUrl string) (*SocketIOClient, error) {
+	return DefaultSocketIO.NewSocketIOUrl(serverUrl)
+}
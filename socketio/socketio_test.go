@@ -0,0 +1,186 @@
+package socketio
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	upgrade := wstest.Handler(t, func(conn *websocket.Conn, r *http.Request) {
+		handler(conn)
+	})
+	return wstest.Serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("EIO") != "4" {
+			t.Errorf("missing EIO param: %s", r.URL)
+		}
+		upgrade(w, r)
+	}))
+}
+
+func readText(t *testing.T, conn *websocket.Conn) string {
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Error(err)
+	}
+	return string(msg)
+}
+
+func TestPacketEncode(t *testing.T) {
+	p := &packet{
+		typ: packetEvent,
+		namespace: "/chat",
+		id: 12,
+		data: []interface{}{"file", []byte{1, 2}},
+	}
+	msg, buffers, err := p.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != `51-/chat,12["file",{"_placeholder":true,"num":0}]` {
+		t.Fatalf("bad encoding: %s", msg)
+	}
+	if len(buffers) != 1 || !bytes.Equal(buffers[0], []byte{1, 2}) {
+		t.Fatalf("bad buffers: %v", buffers)
+	}
+}
+
+func TestPacketDecode(t *testing.T) {
+	p, err := decodePacket(`51-/chat,12["file",{"_placeholder":true,"num":0}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.typ != packetBinaryEvent || p.namespace != "/chat" || p.id != 12 || p.attachments != 1 {
+		t.Fatalf("bad packet: %+v", p)
+	}
+	data := reconstruct(p.data, [][]byte{{1, 2}})
+	expected := []interface{}{"file", []byte{1, 2}}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("reconstruct = %v", data)
+	}
+	p, err = decodePacket(`2["hello"]`)
+	if err != nil || p.namespace != "/" || p.id != -1 {
+		t.Fatalf("bad packet: %+v %v", p, err)
+	}
+	for _, bad := range []string{"", "9", "5[]", `2["x"`} {
+		if _, err := decodePacket(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestEmitAndEvents(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`0{"sid":"a","pingInterval":25000,"pingTimeout":20000}`))
+		if msg := readText(t, conn); msg != `40{"token":"x"}` {
+			t.Errorf("expected namespace connect, got %q", msg)
+			return
+		}
+		// bad packets are skipped.
+		conn.WriteMessage(websocket.TextMessage, []byte(`4x`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`40{"sid":"b"}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`42["hello","world"]`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`451-["bin",{"_placeholder":true,"num":0}]`))
+		conn.WriteMessage(websocket.BinaryMessage, []byte{7})
+		if msg := readText(t, conn); msg != `420["ping",1]` {
+			t.Errorf("expected emit with ack, got %q", msg)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`430["pong"]`))
+		<-done
+	})
+	defer close(done)
+	client, err := NewSocketIOUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 2)
+	ns := client.Of("/", map[string]interface{}{"token": "x"})
+	ns.BindFunc("hello", func(e ws.Event) {
+		got <- e
+	})
+	ns.BindFunc("bin", func(e ws.Event) {
+		got <- e
+	})
+	for _, expected := range []interface{}{"world", []byte{7}} {
+		select {
+		case e := <-got:
+			if args := e.GetData().([]interface{}); !reflect.DeepEqual(args[0], expected) {
+				t.Fatalf("%s: bad args: %v", e.GetEvent(), args)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	args, err := ns.EmitWithAck("ping", 1).Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || args[0] != "pong" {
+		t.Fatalf("bad ack: %v", args)
+	}
+}
+
+func TestServerPings(t *testing.T) {
+	var conns int32
+	done := make(chan struct{})
+	pongs := make(chan string, 10)
+	u := testServer(t, func(conn *websocket.Conn) {
+		if atomic.AddInt32(&conns, 1) > 1 {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`0{"sid":"a","pingInterval":1000,"pingTimeout":1000}`))
+		go func() {
+			for {
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				pongs <- string(msg)
+			}
+		}()
+		// late pings, still within pingInterval + pingTimeout.
+		ticker := time.NewTicker(1600 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				conn.WriteMessage(websocket.TextMessage, []byte("2"))
+			case <-done:
+				return
+			}
+		}
+	})
+	client, err := NewSocketIOUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	// the pings keep the idle connection up.
+	for i := 0; i < 5; i++ {
+		select {
+		case msg := <-pongs:
+			if msg != "3" {
+				t.Fatalf("expected pong, got %q", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for pong")
+		}
+	}
+	close(done)
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("expected one connection, got %d", n)
+	}
+}
@@ -0,0 +1,63 @@
+package stomp
+
+import (
+	"fmt"
+)
+
+// Event wraps a received frame, MESSAGE frames are routed to the subscription's
+// channel by destination.
+type Event struct {
+	*Frame
+	Channel    string
+	proto      *stompProtocol
+}
+
+func (e *Event) GetEvent() string {
+	return e.Command
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Command = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Channel
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return string(e.Body)
+}
+
+func (e *Event) SetData(data interface{}) {
+	switch data := data.(type) {
+	case []byte:
+		e.Body = data
+	case string:
+		e.Body = []byte(data)
+	default:
+		e.Body = []byte(fmt.Sprint(data))
+	}
+}
+
+func (e *Event) GetDataString() string {
+	return string(e.Body)
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Body = []byte(data)
+}
+
+// Acknowledge the message, for subscriptions with "client" or
+// "client-individual" ack modes.
+func (e *Event) Ack() {
+	e.proto.sendAck("ACK", e.Frame)
+}
+
+// Reject the message.
+func (e *Event) Nack() {
+	e.proto.sendAck("NACK", e.Frame)
+}
@@ -0,0 +1,156 @@
+package stomp
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Frame struct {
+	Command  string
+	Header   map[string]string
+	Body     []byte
+}
+
+var (
+	headerEscaper = strings.NewReplacer("\\", "\\\\", "\r", "\\r", "\n", "\\n", ":", "\\c")
+	headerUnescaper = strings.NewReplacer("\\\\", "\\", "\\r", "\r", "\\n", "\n", "\\c", ":")
+)
+
+// CONNECT and CONNECTED frames don't escape headers.
+func escapeHeaders(command string) bool {
+	return command != "CONNECT" && command != "CONNECTED"
+}
+
+func NewFrame(command string, header ...string) *Frame {
+	f := &Frame{
+		Command: command,
+		Header: make(map[string]string, len(header) / 2),
+	}
+	for i := 0; i + 1 < len(header); i += 2 {
+		f.Header[header[i]] = header[i+1]
+	}
+	return f
+}
+
+func (f *Frame) Get(name string) string {
+	return f.Header[name]
+}
+
+func (f *Frame) Set(name string, value string) {
+	if f.Header == nil {
+		f.Header = make(map[string]string)
+	}
+	f.Header[name] = value
+}
+
+func (f *Frame) Encode() []byte {
+	var b bytes.Buffer
+	escape := escapeHeaders(f.Command)
+	b.WriteString(f.Command)
+	b.WriteByte('\n')
+	// sort headers to keep the encoding stable.
+	names := make([]string, 0, len(f.Header))
+	for name := range f.Header {
+		if name != "content-length" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := f.Header[name]
+		if escape {
+			name = headerEscaper.Replace(name)
+			value = headerEscaper.Replace(value)
+		}
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	if len(f.Body) > 0 {
+		b.WriteString("content-length:")
+		b.WriteString(strconv.Itoa(len(f.Body)))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	b.Write(f.Body)
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+func readLine(buf []byte) (string, []byte, error) {
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 {
+		return "", nil, fmt.Errorf("stomp: incomplete frame")
+	}
+	line := buf[:i]
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	return string(line), buf[i+1:], nil
+}
+
+// Decode all frames in buf, heart-beats (empty lines) are skipped.
+func DecodeFrames(buf []byte) ([]*Frame, error) {
+	var frames []*Frame
+	for {
+		// skip heart-beats
+		buf = bytes.TrimLeft(buf, "\r\n")
+		if len(buf) == 0 {
+			return frames, nil
+		}
+		f, rest, err := decodeFrame(buf)
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, f)
+		buf = rest
+	}
+}
+
+func decodeFrame(buf []byte) (*Frame, []byte, error) {
+	command, buf, err := readLine(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := NewFrame(command)
+	escape := escapeHeaders(command)
+	for {
+		var line string
+		line, buf, err = readLine(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		if line == "" {
+			break
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			return nil, nil, fmt.Errorf("stomp: bad header line: %q", line)
+		}
+		name, value := line[:i], line[i+1:]
+		if escape {
+			name = headerUnescaper.Replace(name)
+			value = headerUnescaper.Replace(value)
+		}
+		// first header wins
+		if _, ok := f.Header[name]; !ok {
+			f.Header[name] = value
+		}
+	}
+	if length, ok := f.Header["content-length"]; ok {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 || n >= len(buf) || buf[n] != 0 {
+			return nil, nil, fmt.Errorf("stomp: bad content-length: %q", length)
+		}
+		f.Body = buf[:n]
+		return f, buf[n+1:], nil
+	}
+	i := bytes.IndexByte(buf, 0)
+	if i < 0 {
+		return nil, nil, fmt.Errorf("stomp: missing frame terminator")
+	}
+	f.Body = buf[:i]
+	return f, buf[i+1:], nil
+}
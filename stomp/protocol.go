@@ -0,0 +1,268 @@
+package stomp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Codec for STOMP 1.2 frames.
+type stompProtocol struct {
+	client           *ws.ProtocolClient
+	cf               StompConfig
+	mu               sync.Mutex
+	nextId           uint64
+	subs             map[string]string  // subscription id -> destination
+	receipts         map[string]*Receipt
+	connected        bool
+	clientHeartbeat  bool
+	serverHeartbeat  bool
+	sendInterval     time.Duration
+}
+
+func (p *stompProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *stompProtocol) makeId(prefix string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextId++
+	return prefix + strconv.FormatUint(p.nextId, 10)
+}
+
+func (p *stompProtocol) forget(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.receipts, id)
+}
+
+func (p *stompProtocol) Opened() bool {
+	// wait for CONNECTED.
+	return false
+}
+
+func (p *stompProtocol) Closed() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.connected = false
+	// fail all frames waiting for a receipt.
+	for id, r := range p.receipts {
+		close(r.done)
+		delete(p.receipts, id)
+	}
+}
+
+func (p *stompProtocol) HandshakeFrame() []byte {
+	f := NewFrame("CONNECT",
+		"accept-version", "1.2",
+		"host", p.cf.Host,
+	)
+	if p.cf.Login != "" {
+		f.Set("login", p.cf.Login)
+		f.Set("passcode", p.cf.Passcode)
+	}
+	hb := p.cf.ActivityTimeout.Milliseconds()
+	f.Set("heart-beat", fmt.Sprintf("%d,%d", hb, hb))
+	return f.Encode()
+}
+
+func parseHeartBeat(value string) (time.Duration, time.Duration) {
+	var sx, sy int64
+	parts := strings.SplitN(value, ",", 2)
+	if len(parts) == 2 {
+		sx, _ = strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		sy, _ = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	}
+	return time.Duration(sx) * time.Millisecond, time.Duration(sy) * time.Millisecond
+}
+
+func maxDuration(a time.Duration, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Negotiated heart-beats: how often we send, how long the server can be
+// silent, zero if that side has them disabled.
+func negotiateHeartBeat(hb time.Duration, sx time.Duration, sy time.Duration) (time.Duration, time.Duration) {
+	var send, recv time.Duration
+	if hb > 0 && sy > 0 {
+		send = maxDuration(hb, sy)
+	}
+	if hb > 0 && sx > 0 {
+		recv = maxDuration(hb, sx)
+	}
+	return send, recv
+}
+
+func (p *stompProtocol) handleConnected(f *Frame) {
+	sx, sy := parseHeartBeat(f.Get("heart-beat"))
+	send, recv := negotiateHeartBeat(p.cf.ActivityTimeout, sx, sy)
+	p.mu.Lock()
+	p.clientHeartbeat = send > 0
+	p.serverHeartbeat = recv > 0
+	p.sendInterval = send
+	p.connected = true
+	p.mu.Unlock()
+	if recv > 0 {
+		// allow for some network delay.
+		p.client.Socket().SetPingTimeout(recv * 2)
+	}
+}
+
+func (p *stompProtocol) ActivityTimeout(e ws.Event) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sendInterval
+}
+
+func (p *stompProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	if e, ok := e.(*Event); ok && e.Frame != nil {
+		return e.Frame.Encode(), nil
+	}
+	f := NewFrame("SEND", "destination", e.GetChannel())
+	f.Body = []byte(e.GetDataString())
+	return f.Encode(), nil
+}
+
+func (p *stompProtocol) DecodeEvent(msg []byte) (ws.Event, error) {
+	f, _, err := decodeFrame(msg)
+	if err != nil {
+		return nil, err
+	}
+	return p.event(f), nil
+}
+
+func (p *stompProtocol) DecodeEvents(msg []byte) ([]ws.Event, error) {
+	p.mu.Lock()
+	connected := p.connected
+	p.mu.Unlock()
+	// any frame or heart-beat from the server shows it's alive.
+	if connected {
+		p.client.Socket().HandlePong()
+	}
+	frames, err := DecodeFrames(msg)
+	events := make([]ws.Event, 0, len(frames))
+	for _, f := range frames {
+		events = append(events, p.event(f))
+	}
+	return events, err
+}
+
+func (p *stompProtocol) event(f *Frame) *Event {
+	event := &Event{
+		Frame: f,
+		proto: p,
+	}
+	switch f.Command {
+	case "MESSAGE":
+		p.mu.Lock()
+		event.Channel = p.subs[f.Get("subscription")]
+		p.mu.Unlock()
+	case "RECEIPT":
+		event.Channel = p.handleReceipt(f)
+	}
+	return event
+}
+
+func (p *stompProtocol) handleReceipt(f *Frame) string {
+	id := f.Get("receipt-id")
+	p.mu.Lock()
+	r := p.receipts[id]
+	delete(p.receipts, id)
+	destination := p.subs[id]
+	p.mu.Unlock()
+	if r != nil {
+		r.done <- true
+	}
+	return destination
+}
+
+func (p *stompProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	event := e.(*Event)
+	switch event.Command {
+	case "CONNECTED":
+		p.handleConnected(event.Frame)
+		return ws.KindConnected, nil
+	case "RECEIPT":
+		// subscribe receipt
+		if event.Channel != "" {
+			return ws.KindSubscribed, nil
+		}
+	case "ERROR":
+		log.Println("STOMP error:", event.Get("message"), string(event.Body))
+		return ws.KindEvent, ws.ErrDelayReconnect
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *stompProtocol) SubscribeFrame(destination string) []byte {
+	s, ok := p.client.FindChannel(destination).(*Subscription)
+	if !ok {
+		return nil
+	}
+	f := NewFrame("SUBSCRIBE", s.header...)
+	f.Set("id", s.Id)
+	f.Set("destination", s.Destination)
+	f.Set("ack", s.AckMode)
+	f.Set("receipt", s.Id)
+	return f.Encode()
+}
+
+func (p *stompProtocol) UnsubscribeFrame(destination string) []byte {
+	s, ok := p.client.FindChannel(destination).(*Subscription)
+	if !ok {
+		return nil
+	}
+	return NewFrame("UNSUBSCRIBE", "id", s.Id).Encode()
+}
+
+func (p *stompProtocol) SendPing() {
+	p.mu.Lock()
+	clientHeartbeat := p.clientHeartbeat
+	serverHeartbeat := p.serverHeartbeat
+	p.mu.Unlock()
+	// send heart-beat, unless the server doesn't want them.
+	if clientHeartbeat {
+		p.client.SendMessage([]byte("\n"))
+	}
+	if !serverHeartbeat {
+		// server doesn't send heart-beats, don't wait for one.
+		p.client.Socket().HandlePong()
+	}
+}
+
+func (p *stompProtocol) PingFrame() []byte {
+	return []byte("\n")
+}
+
+func (p *stompProtocol) PongFrame() []byte {
+	return nil
+}
+
+func (p *stompProtocol) NewChannel(destination string) ws.Channel {
+	return p.newSubscription(destination, AckAuto)
+}
+
+func (p *stompProtocol) newSubscription(destination string, ackMode string, header ...string) *Subscription {
+	sub := newSubscription(p.makeId("sub-"), destination, ackMode, p, header...)
+	p.mu.Lock()
+	p.subs[sub.Id] = destination
+	p.mu.Unlock()
+	return sub
+}
+
+func (p *stompProtocol) sendAck(command string, msg *Frame) {
+	ack := msg.Get("ack")
+	if ack == "" {
+		return
+	}
+	p.client.SendMessage(NewFrame(command, "id", ack).Encode())
+}
@@ -0,0 +1,145 @@
+package stomp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrDisconnected = errors.New("stomp: disconnected before receipt")
+)
+
+// Receipt future for a frame sent with a "receipt" header.
+type Receipt struct {
+	Id      string
+	proto   *stompProtocol
+	done    chan bool
+}
+
+// Wait for the server to confirm the frame.
+func (r *Receipt) Wait(ctx context.Context) error {
+	select {
+	case _, ok := <-r.done:
+		if !ok {
+			return ErrDisconnected
+		}
+		return nil
+	case <-ctx.Done():
+		r.proto.forget(r.Id)
+		return ctx.Err()
+	}
+}
+
+type StompClient struct {
+	*ws.ProtocolClient
+	proto  *stompProtocol
+}
+
+// Send a frame as an event, so interceptors see it.
+func (c *StompClient) SendFrame(f *Frame) {
+	c.SendEvent(&Event{
+		Frame: f,
+		Channel: f.Get("destination"),
+	})
+}
+
+// Send a message to a destination, extra headers are given as name/value pairs.
+func (c *StompClient) Send(destination string, body []byte, header ...string) {
+	f := NewFrame("SEND", header...)
+	f.Set("destination", destination)
+	f.Body = body
+	c.SendFrame(f)
+}
+
+// Send a message and request a receipt from the server.
+func (c *StompClient) SendWithReceipt(destination string, body []byte, header ...string) *Receipt {
+	f := NewFrame("SEND", header...)
+	f.Set("destination", destination)
+	f.Body = body
+	return c.SendFrameWithReceipt(f)
+}
+
+func (c *StompClient) SendFrameWithReceipt(f *Frame) *Receipt {
+	r := &Receipt{
+		Id: c.proto.makeId("receipt-"),
+		proto: c.proto,
+		done: make(chan bool, 1),
+	}
+	c.proto.mu.Lock()
+	c.proto.receipts[r.Id] = r
+	c.proto.mu.Unlock()
+	f.Set("receipt", r.Id)
+	c.SendFrame(f)
+	return r
+}
+
+// Subscribe to a destination with an ack mode and extra SUBSCRIBE headers.
+func (c *StompClient) SubscribeAck(destination string, ackMode string, header ...string) *Subscription {
+	if sub, ok := c.FindChannel(destination).(*Subscription); ok {
+		return sub
+	}
+	// create a new subscription.
+	sub := c.proto.newSubscription(destination, ackMode, header...)
+	c.AddChannel(destination, sub)
+	return sub
+}
+
+func (c *StompClient) Subscribe(destination string) ws.Channel {
+	return c.SubscribeAck(destination, AckAuto)
+}
+
+func (c *StompClient) Unsubscribe(destination string) {
+	sub, ok := c.FindChannel(destination).(*Subscription)
+	c.ProtocolClient.Unsubscribe(destination)
+	if ok {
+		c.proto.mu.Lock()
+		delete(c.proto.subs, sub.Id)
+		c.proto.mu.Unlock()
+	}
+}
+
+type StompConfig struct {
+	ws.Config
+	Host              string  // virtual host, defaults to the url host.
+	Login             string
+	Passcode          string
+}
+
+var (
+	DefaultStomp = StompConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 10,
+			PingTimeout:     time.Second * 30,
+			ClientHeartbeat: true,
+			Subprotocols:    []string{"v12.stomp"},
+		},
+	}
+)
+
+func (cf StompConfig) NewStompUrl(stompUrl string) (*StompClient, error) {
+	u, err := url.Parse(stompUrl)
+	if err != nil {
+		return nil, err
+	}
+	if cf.Host == "" {
+		cf.Host = u.Hostname()
+	}
+	proto := &stompProtocol{
+		cf: cf,
+		subs: make(map[string]string),
+		receipts: make(map[string]*Receipt),
+	}
+	return &StompClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		proto: proto,
+	}, nil
+}
+
+func NewStompUrl(stompUrl string) (*StompClient, error) {
+	return DefaultStomp.NewStompUrl(stompUrl)
+}
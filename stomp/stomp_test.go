@@ -0,0 +1,137 @@
+package stomp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"context"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler, "v12.stomp")
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) *Frame {
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		frames, err := DecodeFrames(msg)
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		// skip heart-beats
+		if len(frames) > 0 {
+			return frames[0]
+		}
+	}
+}
+
+func TestFrameCodec(t *testing.T) {
+	f := NewFrame("SEND", "destination", "/queue/a:b")
+	f.Body = []byte("hello\x00world")
+	buf := f.Encode()
+	expected := "SEND\ndestination:/queue/a\\cb\ncontent-length:11\n\nhello\x00world\x00"
+	if string(buf) != expected {
+		t.Fatalf("bad encoding: %q", buf)
+	}
+	frames, err := DecodeFrames(append([]byte("\n\r\n"), append(buf, []byte("\nRECEIPT\nreceipt-id:1\n\n\x00")...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(frames))
+	}
+	if frames[0].Get("destination") != "/queue/a:b" || string(frames[0].Body) != "hello\x00world" {
+		t.Fatalf("bad frame: %+v", frames[0])
+	}
+	if frames[1].Command != "RECEIPT" || frames[1].Get("receipt-id") != "1" {
+		t.Fatalf("bad frame: %+v", frames[1])
+	}
+	for _, bad := range []string{"SEND\n", "SEND\nbad\n\n\x00", "SEND\ncontent-length:5\n\nab\x00", "SEND\n\nbody"} {
+		if _, err := DecodeFrames([]byte(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestNegotiateHeartBeat(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		hb, sx, sy  time.Duration
+		send, recv  time.Duration
+	}{
+		{10 * s, 5 * s, 20 * s, 20 * s, 10 * s},
+		// server doesn't want heart-beats.
+		{10 * s, 5 * s, 0, 0, 10 * s},
+		// server doesn't send heart-beats.
+		{10 * s, 0, 5 * s, 10 * s, 0},
+		// client has them disabled.
+		{0, 5 * s, 5 * s, 0, 0},
+	}
+	for _, test := range tests {
+		send, recv := negotiateHeartBeat(test.hb, test.sx, test.sy)
+		if send != test.send || recv != test.recv {
+			t.Errorf("negotiateHeartBeat(%v, %v, %v) = %v, %v", test.hb, test.sx, test.sy, send, recv)
+		}
+	}
+}
+
+func TestSubscribeAndReceipt(t *testing.T) {
+	done := make(chan struct{})
+	u := testServer(t, func(conn *websocket.Conn) {
+		connect := readFrame(t, conn)
+		if connect == nil || connect.Command != "CONNECT" || connect.Get("accept-version") != "1.2" {
+			t.Errorf("expected CONNECT, got %+v", connect)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, NewFrame("CONNECTED", "version", "1.2", "heart-beat", "0,0").Encode())
+		sub := readFrame(t, conn)
+		if sub == nil || sub.Command != "SUBSCRIBE" || sub.Get("destination") != "/topic/a" {
+			t.Errorf("expected SUBSCRIBE, got %+v", sub)
+			return
+		}
+		// bad frames are skipped.
+		conn.WriteMessage(websocket.TextMessage, []byte("MESSAGE\nbad header\n\n\x00"))
+		conn.WriteMessage(websocket.TextMessage, NewFrame("RECEIPT", "receipt-id", sub.Get("receipt")).Encode())
+		msg := NewFrame("MESSAGE", "subscription", sub.Get("id"), "destination", "/topic/a", "message-id", "1")
+		msg.Body = []byte("hi")
+		conn.WriteMessage(websocket.TextMessage, msg.Encode())
+		send := readFrame(t, conn)
+		if send == nil || send.Command != "SEND" || send.Get("receipt") == "" || string(send.Body) != "out" {
+			t.Errorf("expected SEND, got %+v", send)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, NewFrame("RECEIPT", "receipt-id", send.Get("receipt")).Encode())
+		<-done
+	})
+	defer close(done)
+	client, err := NewStompUrl(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 1)
+	client.Subscribe("/topic/a").BindFunc("MESSAGE", func(e ws.Event) {
+		got <- e
+	})
+	select {
+	case e := <-got:
+		if e.GetDataString() != "hi" {
+			t.Fatalf("bad message: %q", e.GetDataString())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.SendWithReceipt("/queue/b", []byte("out")).Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,45 @@
+package stomp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+)
+
+const (
+	AckAuto = "auto"
+	AckClient = "client"
+	AckClientIndividual = "client-individual"
+)
+
+type Subscription struct {
+	*ws.PublicChannel
+	Id           string
+	Destination  string
+	AckMode      string
+	header       []string
+}
+
+func (s *Subscription) UpdateClientState(connected bool) {
+	if connected {
+		// Client connected, (re)subscribe.
+		s.Subscribe()
+	} else {
+		s.SetActive(false)
+	}
+}
+
+func newSubscription(id string, destination string, ackMode string, proto *stompProtocol, header ...string) *Subscription {
+	if ackMode == "" {
+		ackMode = AckAuto
+	}
+	return &Subscription{
+		PublicChannel: ws.NewPublicChannel(destination, proto.client),
+		Id: id,
+		Destination: destination,
+		AckMode: ackMode,
+		header: header,
+	}
+}
+
+func NewSubscription(id string, destination string, ackMode string, client *StompClient, header ...string) *Subscription {
+	return newSubscription(id, destination, ackMode, client.proto, header...)
+}
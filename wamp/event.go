@@ -0,0 +1,57 @@
+package wamp
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// EVENT received for a subscribed topic.
+type Event struct {
+	Topic        string
+	Event        string
+	Publication  uint64
+	Args         []interface{}
+	KwArgs       map[string]interface{}
+	Details      map[string]interface{}
+	msg          []interface{}  // other messages, not sent to handlers
+}
+
+func (e *Event) GetEvent() string {
+	return e.Event
+}
+
+func (e *Event) SetEvent(event string) {
+	e.Event = event
+}
+
+func (e *Event) GetChannel() string {
+	return e.Topic
+}
+
+func (e *Event) SetChannel(channel string) {
+	e.Topic = channel
+}
+
+func (e *Event) GetData() interface{} {
+	return e.Args
+}
+
+func (e *Event) SetData(data interface{}) {
+	if args, ok := data.([]interface{}); ok {
+		e.Args = args
+	} else {
+		e.Args = []interface{}{data}
+	}
+}
+
+func (e *Event) GetDataString() string {
+	buf, err := json.Marshal(e.Args)
+	if err != nil {
+		log.Fatal("JSON Marshaller failed:", err)
+	}
+	return string(buf)
+}
+
+func (e *Event) SetDataString(data string) {
+	e.Args = []interface{}{data}
+}
@@ -0,0 +1,117 @@
+package wamp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WAMP message codes
+const (
+	msgHello = 1
+	msgWelcome = 2
+	msgAbort = 3
+	msgGoodbye = 6
+	msgError = 8
+	msgPublish = 16
+	msgPublished = 17
+	msgSubscribe = 32
+	msgSubscribed = 33
+	msgUnsubscribe = 34
+	msgUnsubscribed = 35
+	msgEvent = 36
+	msgCall = 48
+	msgResult = 50
+	msgRegister = 64
+	msgRegistered = 65
+	msgUnregister = 66
+	msgUnregistered = 67
+	msgInvocation = 68
+	msgYield = 70
+)
+
+// Error reply from the router or a callee.
+type Error struct {
+	Uri     string
+	Args    []interface{}
+	KwArgs  map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	if len(e.Args) > 0 {
+		return fmt.Sprintf("wamp: %s: %v", e.Uri, e.Args[0])
+	}
+	return "wamp: " + e.Uri
+}
+
+type Result struct {
+	Args     []interface{}
+	KwArgs   map[string]interface{}
+	Details  map[string]interface{}
+}
+
+type Invocation struct {
+	Procedure  string
+	Args       []interface{}
+	KwArgs     map[string]interface{}
+	Details    map[string]interface{}
+}
+
+// Callee procedure.  Return an *Error to send a specific error uri.
+type InvocationHandler func(inv *Invocation) (*Result, error)
+
+func toId(v interface{}) (uint64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		return uint64(n), err == nil
+	case int64:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	case float64:
+		return uint64(v), true
+	}
+	return 0, false
+}
+
+// Get element i of msg, nil if missing.
+func elem(msg []interface{}, i int) interface{} {
+	if i < len(msg) {
+		return msg[i]
+	}
+	return nil
+}
+
+func elemId(msg []interface{}, i int) uint64 {
+	id, _ := toId(elem(msg, i))
+	return id
+}
+
+func elemDict(msg []interface{}, i int) map[string]interface{} {
+	d, _ := elem(msg, i).(map[string]interface{})
+	return d
+}
+
+func elemList(msg []interface{}, i int) []interface{} {
+	l, _ := elem(msg, i).([]interface{})
+	return l
+}
+
+func elemString(msg []interface{}, i int) string {
+	s, _ := elem(msg, i).(string)
+	return s
+}
+
+// Append optional args & kwargs.
+func appendArgs(msg []interface{}, args []interface{}, kwargs map[string]interface{}) []interface{} {
+	if len(kwargs) > 0 {
+		if args == nil {
+			args = []interface{}{}
+		}
+		return append(msg, args, kwargs)
+	}
+	if len(args) > 0 {
+		return append(msg, args)
+	}
+	return msg
+}
@@ -0,0 +1,336 @@
+package wamp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Minimal MessagePack codec for the generic values used in WAMP messages.
+
+const (
+	// max nesting of arrays and maps.
+	MSGPACK_MAX_DEPTH = 64
+)
+
+var (
+	errMsgpackShort = errors.New("msgpack: short buffer")
+	errMsgpackDepth = errors.New("msgpack: nested too deep")
+)
+
+func msgpackEncode(b *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		b.WriteByte(0xc0)
+	case bool:
+		if v {
+			b.WriteByte(0xc3)
+		} else {
+			b.WriteByte(0xc2)
+		}
+	case int:
+		msgpackInt(b, int64(v))
+	case int32:
+		msgpackInt(b, int64(v))
+	case int64:
+		msgpackInt(b, v)
+	case uint:
+		msgpackUint(b, uint64(v))
+	case uint32:
+		msgpackUint(b, uint64(v))
+	case uint64:
+		msgpackUint(b, v)
+	case float32:
+		b.WriteByte(0xca)
+		binary.Write(b, binary.BigEndian, math.Float32bits(v))
+	case float64:
+		// integral values are sent as integers.
+		if v == math.Trunc(v) && math.Abs(v) < 1 << 53 {
+			msgpackInt(b, int64(v))
+			return nil
+		}
+		b.WriteByte(0xcb)
+		binary.Write(b, binary.BigEndian, math.Float64bits(v))
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			msgpackInt(b, n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return msgpackEncode(b, f)
+	case string:
+		msgpackHeader(b, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		b.WriteString(v)
+	case []byte:
+		msgpackHeader(b, len(v), 0, 0, 0xc4, 0xc5, 0xc6)
+		b.Write(v)
+	case []interface{}:
+		msgpackHeader(b, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, e := range v {
+			if err := msgpackEncode(b, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		msgpackHeader(b, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for k, e := range v {
+			msgpackEncode(b, k)
+			if err := msgpackEncode(b, e); err != nil {
+				return err
+			}
+		}
+	default:
+		// convert other values to generic values with JSON.
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic interface{}
+		dec := json.NewDecoder(bytes.NewReader(buf))
+		dec.UseNumber()
+		if err := dec.Decode(&generic); err != nil {
+			return err
+		}
+		return msgpackEncode(b, generic)
+	}
+	return nil
+}
+
+// Write a length header, fixed formats are used if fixMax > 0.
+func msgpackHeader(b *bytes.Buffer, n int, fix byte, fixMax int, c8 byte, c16 byte, c32 byte) {
+	switch {
+	case n < fixMax:
+		b.WriteByte(fix | byte(n))
+	case n <= math.MaxUint8 && c8 != 0:
+		b.WriteByte(c8)
+		b.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		b.WriteByte(c16)
+		binary.Write(b, binary.BigEndian, uint16(n))
+	default:
+		b.WriteByte(c32)
+		binary.Write(b, binary.BigEndian, uint32(n))
+	}
+}
+
+func msgpackUint(b *bytes.Buffer, n uint64) {
+	switch {
+	case n < 128:
+		b.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		b.WriteByte(0xcc)
+		b.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		b.WriteByte(0xcd)
+		binary.Write(b, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		b.WriteByte(0xce)
+		binary.Write(b, binary.BigEndian, uint32(n))
+	default:
+		b.WriteByte(0xcf)
+		binary.Write(b, binary.BigEndian, n)
+	}
+}
+
+func msgpackInt(b *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0:
+		msgpackUint(b, uint64(n))
+	case n >= -32:
+		b.WriteByte(byte(n))
+	case n >= math.MinInt8:
+		b.WriteByte(0xd0)
+		b.WriteByte(byte(n))
+	case n >= math.MinInt16:
+		b.WriteByte(0xd1)
+		binary.Write(b, binary.BigEndian, int16(n))
+	case n >= math.MinInt32:
+		b.WriteByte(0xd2)
+		binary.Write(b, binary.BigEndian, int32(n))
+	default:
+		b.WriteByte(0xd3)
+		binary.Write(b, binary.BigEndian, n)
+	}
+}
+
+type msgpackDecoder struct {
+	buf    []byte
+	depth  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if len(d.buf) < n {
+		return nil, errMsgpackShort
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v << 8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *msgpackDecoder) int(n int) (int64, error) {
+	v, err := d.uint(n)
+	if err != nil {
+		return 0, err
+	}
+	// sign extend
+	shift := uint(64 - n * 8)
+	return int64(v << shift) >> shift, nil
+}
+
+// Check a container of n elements of at least size bytes each before
+// allocating it.
+func (d *msgpackDecoder) enter(n int, size int) error {
+	if n < 0 || n > len(d.buf) / size {
+		return errMsgpackShort
+	}
+	if d.depth >= MSGPACK_MAX_DEPTH {
+		return errMsgpackDepth
+	}
+	d.depth++
+	return nil
+}
+
+func (d *msgpackDecoder) array(n int) (interface{}, error) {
+	if err := d.enter(n, 1); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (d *msgpackDecoder) object(n int) (interface{}, error) {
+	if err := d.enter(n, 2); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+func (d *msgpackDecoder) str(n int) (interface{}, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) bin(n int) (interface{}, error) {
+	b, err := d.next(n)
+	return append([]byte(nil), b...), err
+}
+
+// Read length of the given size and call fn with it.
+func (d *msgpackDecoder) sized(size int, fn func(int) (interface{}, error)) (interface{}, error) {
+	n, err := d.uint(size)
+	if err != nil {
+		return nil, err
+	}
+	return fn(int(n))
+}
+
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c & 0xf0 == 0x80:
+		return d.object(int(c & 0x0f))
+	case c & 0xf0 == 0x90:
+		return d.array(int(c & 0x0f))
+	case c & 0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4:
+		return d.sized(1, d.bin)
+	case 0xc5:
+		return d.sized(2, d.bin)
+	case 0xc6:
+		return d.sized(4, d.bin)
+	case 0xca:
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.uint(1 << (c - 0xcc))
+		if v > math.MaxInt64 {
+			return v, err
+		}
+		return int64(v), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		return d.int(1 << (c - 0xd0))
+	case 0xd9:
+		return d.sized(1, d.str)
+	case 0xda:
+		return d.sized(2, d.str)
+	case 0xdb:
+		return d.sized(4, d.str)
+	case 0xdc:
+		return d.sized(2, d.array)
+	case 0xdd:
+		return d.sized(4, d.array)
+	case 0xde:
+		return d.sized(2, d.object)
+	case 0xdf:
+		return d.sized(4, d.object)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type: 0x%x", c)
+}
+
+func msgpackDecode(buf []byte) (interface{}, error) {
+	d := &msgpackDecoder{buf: buf}
+	return d.decode()
+}
@@ -0,0 +1,355 @@
+package wamp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"log"
+	"sync"
+)
+
+type replyFn func(msg []interface{}) error
+
+// Codec for WAMP messages with the negotiated serializer.
+type wampProtocol struct {
+	sync.Mutex
+	client       *ws.ProtocolClient
+	cf           WampConfig
+	serializer   Serializer
+	session      uint64
+	joined       bool
+	nextId       uint64
+	pending      map[uint64]replyFn
+	subs         map[uint64]string  // subscription id -> topic
+	procs        map[string]InvocationHandler
+	regs         map[uint64]string  // registration id -> procedure
+}
+
+func (p *wampProtocol) SetClient(c *ws.ProtocolClient) {
+	p.client = c
+}
+
+func (p *wampProtocol) makeId() uint64 {
+	p.Lock()
+	defer p.Unlock()
+	p.nextId++
+	return p.nextId
+}
+
+func (p *wampProtocol) isJoined() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.joined
+}
+
+func (p *wampProtocol) getSerializer() Serializer {
+	p.Lock()
+	defer p.Unlock()
+	return p.serializer
+}
+
+func (p *wampProtocol) marshal(msg []interface{}) []byte {
+	buf, err := p.getSerializer().Marshal(msg)
+	if err != nil {
+		log.Println("Error encoding message:", err)
+		return nil
+	}
+	return buf
+}
+
+func (p *wampProtocol) send(msg []interface{}) {
+	if buf := p.marshal(msg); buf != nil {
+		p.client.SendMessage(buf)
+	}
+}
+
+// Encode a request, fn is called with the reply or nil on disconnect.
+func (p *wampProtocol) requestFrame(msg []interface{}, fn replyFn) []byte {
+	id := msg[1].(uint64)
+	buf := p.marshal(msg)
+	if buf == nil {
+		return nil
+	}
+	p.Lock()
+	p.pending[id] = fn
+	p.Unlock()
+	return buf
+}
+
+func (p *wampProtocol) request(msg []interface{}, fn replyFn) {
+	if buf := p.requestFrame(msg, fn); buf != nil {
+		p.client.SendMessage(buf)
+	}
+}
+
+func (p *wampProtocol) forget(id uint64) {
+	p.Lock()
+	defer p.Unlock()
+	delete(p.pending, id)
+}
+
+func (p *wampProtocol) Opened() bool {
+	// pick serializer from the negotiated subprotocol.
+	proto := p.client.Socket().Subprotocol()
+	p.Lock()
+	defer p.Unlock()
+	p.serializer = p.cf.Serializers[0]
+	for _, s := range p.cf.Serializers {
+		if s.Subprotocol() == proto {
+			p.serializer = s
+		}
+	}
+	// wait for WELCOME.
+	return false
+}
+
+func (p *wampProtocol) Closed() {
+	p.Lock()
+	p.joined = false
+	pending := p.pending
+	p.pending = make(map[uint64]replyFn)
+	p.subs = make(map[uint64]string)
+	p.regs = make(map[uint64]string)
+	p.Unlock()
+	// fail all requests waiting for a reply.
+	for _, fn := range pending {
+		p.client.SafeCall("", "reply", func() {
+			fn(nil)
+		})
+	}
+}
+
+func (p *wampProtocol) BinaryFrames() bool {
+	return p.getSerializer().Binary()
+}
+
+func (p *wampProtocol) HandshakeFrame() []byte {
+	return p.marshal([]interface{}{msgHello, p.cf.Realm, map[string]interface{}{
+		"roles": map[string]interface{}{
+			"publisher": map[string]interface{}{},
+			"subscriber": map[string]interface{}{},
+			"caller": map[string]interface{}{},
+			"callee": map[string]interface{}{},
+		},
+	}})
+}
+
+// Events are published to their channel, dropped if the session isn't joined.
+func (p *wampProtocol) EncodeEvent(e ws.Event) ([]byte, error) {
+	if !p.isJoined() {
+		return nil, ErrNotJoined
+	}
+	var args []interface{}
+	if data, ok := e.GetData().([]interface{}); ok {
+		args = data
+	} else if e.GetData() != nil {
+		args = []interface{}{e.GetData()}
+	}
+	msg := appendArgs([]interface{}{msgPublish, p.makeId(), map[string]interface{}{}, e.GetChannel()}, args, nil)
+	return p.getSerializer().Marshal(msg)
+}
+
+func (p *wampProtocol) DecodeEvent(buf []byte) (ws.Event, error) {
+	msg, err := p.getSerializer().Unmarshal(buf)
+	if err != nil {
+		return nil, err
+	}
+	if code, _ := toId(elem(msg, 0)); code != msgEvent {
+		// handled by Classify.
+		return &Event{msg: msg}, nil
+	}
+	p.Lock()
+	topic := p.subs[elemId(msg, 1)]
+	p.Unlock()
+	details := elemDict(msg, 3)
+	// pattern subscriptions get the real topic in the details.
+	if topic == "" {
+		topic, _ = details["topic"].(string)
+	}
+	return &Event{
+		Topic: topic,
+		Event: "event",
+		Publication: elemId(msg, 2),
+		Details: details,
+		Args: elemList(msg, 4),
+		KwArgs: elemDict(msg, 5),
+	}, nil
+}
+
+func (p *wampProtocol) DecodeBinary(buf []byte) ([]ws.Event, error) {
+	e, err := p.DecodeEvent(buf)
+	if e == nil {
+		return nil, err
+	}
+	return []ws.Event{e}, err
+}
+
+func (p *wampProtocol) handleWelcome(msg []interface{}) {
+	p.Lock()
+	p.session = elemId(msg, 1)
+	p.joined = true
+	procs := make([]string, 0, len(p.procs))
+	for proc := range p.procs {
+		procs = append(procs, proc)
+	}
+	p.Unlock()
+	// register procedures & subscribe to topics.
+	for _, proc := range procs {
+		p.sendRegister(proc)
+	}
+	p.client.Connected()
+}
+
+func (p *wampProtocol) handleReply(msg []interface{}, idx int) error {
+	id := elemId(msg, idx)
+	p.Lock()
+	fn := p.pending[id]
+	delete(p.pending, id)
+	p.Unlock()
+	var err error
+	if fn != nil {
+		p.client.SafeCall("", "reply", func() {
+			err = fn(msg)
+		})
+	}
+	return err
+}
+
+func (p *wampProtocol) handleInvocation(msg []interface{}) {
+	id := elemId(msg, 1)
+	p.Lock()
+	proc := p.regs[elemId(msg, 2)]
+	h := p.procs[proc]
+	p.Unlock()
+	if h == nil {
+		p.send([]interface{}{msgError, msgInvocation, id, map[string]interface{}{}, "wamp.error.no_such_registration"})
+		return
+	}
+	inv := &Invocation{
+		Procedure: proc,
+		Details: elemDict(msg, 3),
+		Args: elemList(msg, 4),
+		KwArgs: elemDict(msg, 5),
+	}
+	// don't block the socket while the procedure runs.
+	go func() {
+		res, err := h(inv)
+		if err != nil {
+			werr, ok := err.(*Error)
+			if !ok {
+				werr = &Error{
+					Uri: "wamp.error.runtime_error",
+					Args: []interface{}{err.Error()},
+				}
+			}
+			p.send(appendArgs([]interface{}{msgError, msgInvocation, id, map[string]interface{}{}, werr.Uri}, werr.Args, werr.KwArgs))
+			return
+		}
+		if res == nil {
+			res = &Result{}
+		}
+		p.send(appendArgs([]interface{}{msgYield, id, map[string]interface{}{}}, res.Args, res.KwArgs))
+	}()
+}
+
+func (p *wampProtocol) handleMessage(msg []interface{}) error {
+	code, ok := toId(elem(msg, 0))
+	if !ok {
+		log.Println("WAMP bad message:", msg)
+		return nil
+	}
+	switch code {
+	case msgWelcome:
+		p.handleWelcome(msg)
+	case msgAbort:
+		reason := elemString(msg, 2)
+		log.Println("WAMP abort:", reason, elemDict(msg, 1))
+		if reason == "wamp.close.system_shutdown" || reason == "wamp.close.close_realm" {
+			return ws.ErrDelayReconnect
+		}
+		return ws.NewError("Abort: " + reason, false, false, 0)
+	case msgGoodbye:
+		log.Println("WAMP goodbye:", elemString(msg, 2))
+		p.send([]interface{}{msgGoodbye, map[string]interface{}{}, "wamp.close.goodbye_and_out"})
+		return ws.ErrDelayReconnect
+	case msgError:
+		return p.handleReply(msg, 2)
+	case msgSubscribed, msgRegistered, msgResult, msgPublished, msgUnsubscribed, msgUnregistered:
+		return p.handleReply(msg, 1)
+	case msgInvocation:
+		p.handleInvocation(msg)
+	}
+	return nil
+}
+
+func (p *wampProtocol) Classify(e ws.Event) (ws.MessageKind, error) {
+	if e, ok := e.(*Event); ok && e.msg != nil {
+		return ws.KindInternal, p.handleMessage(e.msg)
+	}
+	return ws.KindEvent, nil
+}
+
+func (p *wampProtocol) sendRegister(procedure string) {
+	msg := []interface{}{msgRegister, p.makeId(), map[string]interface{}{}, procedure}
+	p.request(msg, func(reply []interface{}) error {
+		if reply == nil {
+			return nil
+		}
+		if elemId(reply, 0) == msgError {
+			log.Println("WAMP register failed:", procedure, replyError(reply))
+			return nil
+		}
+		p.Lock()
+		p.regs[elemId(reply, 2)] = procedure
+		p.Unlock()
+		return nil
+	})
+}
+
+func (p *wampProtocol) SubscribeFrame(topic string) []byte {
+	sub, _ := p.client.FindChannel(topic).(*Subscription)
+	if sub == nil || !p.isJoined() {
+		return nil
+	}
+	msg := []interface{}{msgSubscribe, p.makeId(), sub.Options, topic}
+	return p.requestFrame(msg, func(reply []interface{}) error {
+		if reply == nil {
+			return nil
+		}
+		if elemId(reply, 0) == msgError {
+			log.Println("WAMP subscribe failed:", topic, replyError(reply))
+			return nil
+		}
+		p.Lock()
+		p.subs[elemId(reply, 2)] = topic
+		p.Unlock()
+		p.client.Subscribed(topic)
+		return nil
+	})
+}
+
+func (p *wampProtocol) UnsubscribeFrame(topic string) []byte {
+	p.Lock()
+	var subId uint64
+	for id, t := range p.subs {
+		if t == topic {
+			subId = id
+			delete(p.subs, id)
+		}
+	}
+	p.Unlock()
+	if subId == 0 {
+		return nil
+	}
+	return p.requestFrame([]interface{}{msgUnsubscribe, p.makeId(), subId}, func([]interface{}) error {
+		return nil
+	})
+}
+
+// no WAMP level heartbeat, use websocket pings.
+func (p *wampProtocol) PingFrame() []byte {
+	return nil
+}
+
+func (p *wampProtocol) PongFrame() []byte {
+	return nil
+}
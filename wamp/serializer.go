@@ -0,0 +1,66 @@
+package wamp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Serializer for WAMP messages, selected with the websocket subprotocol.
+type Serializer interface {
+	Subprotocol() string
+	Binary() bool
+	Marshal(msg []interface{}) ([]byte, error)
+	Unmarshal(buf []byte) ([]interface{}, error)
+}
+
+type JSONSerializer struct{}
+
+func (s JSONSerializer) Subprotocol() string {
+	return "wamp.2.json"
+}
+
+func (s JSONSerializer) Binary() bool {
+	return false
+}
+
+func (s JSONSerializer) Marshal(msg []interface{}) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (s JSONSerializer) Unmarshal(buf []byte) ([]interface{}, error) {
+	var msg []interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	// keep ids exact
+	dec.UseNumber()
+	err := dec.Decode(&msg)
+	return msg, err
+}
+
+type MsgpackSerializer struct{}
+
+func (s MsgpackSerializer) Subprotocol() string {
+	return "wamp.2.msgpack"
+}
+
+func (s MsgpackSerializer) Binary() bool {
+	return true
+}
+
+func (s MsgpackSerializer) Marshal(msg []interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	err := msgpackEncode(&b, msg)
+	return b.Bytes(), err
+}
+
+func (s MsgpackSerializer) Unmarshal(buf []byte) ([]interface{}, error) {
+	v, err := msgpackDecode(buf)
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("wamp: message isn't a list")
+	}
+	return msg, nil
+}
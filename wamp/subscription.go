@@ -0,0 +1,40 @@
+package wamp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+)
+
+type Subscription struct {
+	*ws.PublicChannel
+	Topic    string
+	Options  map[string]interface{}
+}
+
+func (s *Subscription) UpdateClientState(connected bool) {
+	if connected {
+		// Session joined, (re)subscribe.
+		s.Subscribe()
+	} else {
+		s.SetActive(false)
+	}
+}
+
+func (s *Subscription) Unsubscribe() {
+	s.PublicChannel.Unsubscribe()
+	s.SetActive(false)
+}
+
+func newSubscription(topic string, options map[string]interface{}, proto *wampProtocol) *Subscription {
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return &Subscription{
+		PublicChannel: ws.NewPublicChannel(topic, proto.client),
+		Topic: topic,
+		Options: options,
+	}
+}
+
+func NewSubscription(topic string, options map[string]interface{}, client *WampClient) *Subscription {
+	return newSubscription(topic, options, client.proto)
+}
@@ -0,0 +1,180 @@
+package wamp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrNotJoined = errors.New("wamp: session not joined")
+	ErrDisconnected = errors.New("wamp: disconnected before reply")
+)
+
+type WampClient struct {
+	*ws.ProtocolClient
+	cf      WampConfig
+	proto   *wampProtocol
+}
+
+func replyError(msg []interface{}) *Error {
+	return &Error{
+		Uri: elemString(msg, 4),
+		Args: elemList(msg, 5),
+		KwArgs: elemDict(msg, 6),
+	}
+}
+
+// Call a procedure and wait for the result.
+func (c *WampClient) Call(ctx context.Context, procedure string, args []interface{}, kwargs map[string]interface{}) (*Result, error) {
+	p := c.proto
+	if !p.isJoined() {
+		return nil, ErrNotJoined
+	}
+	type result struct {
+		res *Result
+		err error
+	}
+	done := make(chan result, 1)
+	id := p.makeId()
+	msg := appendArgs([]interface{}{msgCall, id, map[string]interface{}{}, procedure}, args, kwargs)
+	p.request(msg, func(reply []interface{}) error {
+		switch {
+		case reply == nil:
+			done <- result{err: ErrDisconnected}
+		case elemId(reply, 0) == msgError:
+			done <- result{err: replyError(reply)}
+		default:
+			done <- result{res: &Result{
+				Details: elemDict(reply, 2),
+				Args: elemList(reply, 3),
+				KwArgs: elemDict(reply, 4),
+			}}
+		}
+		return nil
+	})
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-ctx.Done():
+		p.forget(id)
+		return nil, ctx.Err()
+	}
+}
+
+// Publish an event to a topic, dropped if the session isn't joined.
+func (c *WampClient) Publish(topic string, args []interface{}, kwargs map[string]interface{}) {
+	p := c.proto
+	if !p.isJoined() {
+		return
+	}
+	p.send(appendArgs([]interface{}{msgPublish, p.makeId(), map[string]interface{}{}, topic}, args, kwargs))
+}
+
+// Register a procedure, it is registered again after a reconnect.
+func (c *WampClient) Register(procedure string, h InvocationHandler) {
+	p := c.proto
+	p.Lock()
+	p.procs[procedure] = h
+	joined := p.joined
+	p.Unlock()
+	if joined {
+		p.sendRegister(procedure)
+	}
+}
+
+func (c *WampClient) Unregister(procedure string) {
+	p := c.proto
+	p.Lock()
+	delete(p.procs, procedure)
+	var regId uint64
+	for id, proc := range p.regs {
+		if proc == procedure {
+			regId = id
+			delete(p.regs, id)
+		}
+	}
+	joined := p.joined
+	p.Unlock()
+	if joined && regId != 0 {
+		p.request([]interface{}{msgUnregister, p.makeId(), regId}, func([]interface{}) error {
+			return nil
+		})
+	}
+}
+
+func (c *WampClient) Close() {
+	if c.proto.isJoined() {
+		c.proto.send([]interface{}{msgGoodbye, map[string]interface{}{}, "wamp.close.close_realm"})
+	}
+	c.ProtocolClient.Close()
+}
+
+// Subscribe to a topic with options (e.g. {"match": "prefix"}).
+func (c *WampClient) SubscribeOptions(topic string, options map[string]interface{}) *Subscription {
+	if sub, ok := c.FindChannel(topic).(*Subscription); ok {
+		return sub
+	}
+	// create a new subscription.
+	sub := NewSubscription(topic, options, c)
+	c.AddChannel(topic, sub)
+	return sub
+}
+
+func (c *WampClient) Subscribe(topic string) ws.Channel {
+	return c.SubscribeOptions(topic, nil)
+}
+
+type WampConfig struct {
+	ws.Config
+	Realm             string
+	// Serializers in order of preference.
+	Serializers       []Serializer
+}
+
+var (
+	DefaultWamp = WampConfig{
+		Config: ws.Config{
+			ConnectTimeout:  time.Second * 30,
+			ActivityTimeout: time.Second * 120,
+			PingTimeout:     time.Second * 30,
+		},
+		Serializers:     []Serializer{JSONSerializer{}, MsgpackSerializer{}},
+	}
+)
+
+func (cf WampConfig) NewWampUrl(routerUrl string, realm string) (*WampClient, error) {
+	u, err := url.Parse(routerUrl)
+	if err != nil {
+		return nil, err
+	}
+	cf.Realm = realm
+	if len(cf.Serializers) == 0 {
+		cf.Serializers = DefaultWamp.Serializers
+	}
+	// negotiate serializer with the subprotocol.
+	cf.Subprotocols = nil
+	for _, s := range cf.Serializers {
+		cf.Subprotocols = append(cf.Subprotocols, s.Subprotocol())
+	}
+	proto := &wampProtocol{
+		cf: cf,
+		serializer: cf.Serializers[0],
+		pending: make(map[uint64]replyFn),
+		subs: make(map[uint64]string),
+		procs: make(map[string]InvocationHandler),
+		regs: make(map[uint64]string),
+	}
+	return &WampClient{
+		ProtocolClient: cf.Config.NewProtocolClient(u, proto),
+		cf: cf,
+		proto: proto,
+	}, nil
+}
+
+func NewWampUrl(routerUrl string, realm string) (*WampClient, error) {
+	return DefaultWamp.NewWampUrl(routerUrl, realm)
+}
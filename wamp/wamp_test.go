@@ -0,0 +1,158 @@
+package wamp
+
+import (
+	ws "github.com/Neopallium/websocket-client-go/websocket"
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, s Serializer, handler func(conn *websocket.Conn)) string {
+	return wstest.Server(t, handler, s.Subprotocol())
+}
+
+func readMsg(t *testing.T, conn *websocket.Conn, s Serializer) []interface{} {
+	typ, buf, err := conn.ReadMessage()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	if (typ == websocket.BinaryMessage) != s.Binary() {
+		t.Errorf("wrong message type %d for %s", typ, s.Subprotocol())
+	}
+	msg, err := s.Unmarshal(buf)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	return msg
+}
+
+func writeMsg(conn *websocket.Conn, s Serializer, msg ...interface{}) {
+	buf, _ := s.Marshal(msg)
+	typ := websocket.TextMessage
+	if s.Binary() {
+		typ = websocket.BinaryMessage
+	}
+	conn.WriteMessage(typ, buf)
+}
+
+func TestSerializers(t *testing.T) {
+	msg := []interface{}{msgEvent, uint64(1) << 40, 2, map[string]interface{}{"topic": "a.b"},
+		[]interface{}{"x", -3, 1.5, true, nil}}
+	for _, s := range []Serializer{JSONSerializer{}, MsgpackSerializer{}} {
+		buf, err := s.Marshal(msg)
+		if err != nil {
+			t.Fatal(s.Subprotocol(), err)
+		}
+		got, err := s.Unmarshal(buf)
+		if err != nil {
+			t.Fatal(s.Subprotocol(), err)
+		}
+		if elemId(got, 1) != uint64(1) << 40 || elemDict(got, 3)["topic"] != "a.b" {
+			t.Fatalf("%s: bad message: %v", s.Subprotocol(), got)
+		}
+		args := elemList(got, 4)
+		if len(args) != 5 || args[0] != "x" || args[3] != true || args[4] != nil {
+			t.Fatalf("%s: bad args: %v", s.Subprotocol(), args)
+		}
+	}
+	if _, err := (MsgpackSerializer{}).Unmarshal([]byte{0x92, 0x01}); err == nil {
+		t.Fatal("expected error for short msgpack")
+	}
+}
+
+func TestMsgpackLimits(t *testing.T) {
+	for _, buf := range [][]byte{
+		// array and map headers larger than the message.
+		{0xdd, 0x7f, 0xff, 0xff, 0xff},
+		{0xdf, 0x7f, 0xff, 0xff, 0xff},
+		{0xdc, 0xff, 0xff, 0x01},
+		{0x82, 0xa1, 'a', 0x01},
+		// truncated headers.
+		{0xdd, 0x00, 0x01},
+		{0xdb, 0xff},
+		{0xc6, 0x00, 0x00, 0x00, 0x10, 0x01},
+		// nested too deep.
+		bytes.Repeat([]byte{0x91}, MSGPACK_MAX_DEPTH + 1),
+	} {
+		if _, err := (MsgpackSerializer{}).Unmarshal(buf); err == nil {
+			t.Fatalf("expected error for % x", buf)
+		}
+	}
+	nested := append(bytes.Repeat([]byte{0x91}, MSGPACK_MAX_DEPTH), 0x01)
+	if _, err := (MsgpackSerializer{}).Unmarshal(nested); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testSession(t *testing.T, s Serializer) {
+	done := make(chan struct{})
+	u := testServer(t, s, func(conn *websocket.Conn) {
+		hello := readMsg(t, conn, s)
+		if elemId(hello, 0) != msgHello || elemString(hello, 1) != "realm1" {
+			t.Errorf("expected HELLO, got %v", hello)
+			return
+		}
+		// bad messages are skipped.
+		conn.WriteMessage(websocket.TextMessage, []byte(`not json`))
+		writeMsg(conn, s, "bad")
+		writeMsg(conn, s, msgWelcome, 42, map[string]interface{}{})
+		sub := readMsg(t, conn, s)
+		if elemId(sub, 0) != msgSubscribe || elemString(sub, 3) != "com.test" {
+			t.Errorf("expected SUBSCRIBE, got %v", sub)
+			return
+		}
+		writeMsg(conn, s, msgSubscribed, elemId(sub, 1), 7)
+		writeMsg(conn, s, msgEvent, 7, 1, map[string]interface{}{}, []interface{}{"hi"})
+		call := readMsg(t, conn, s)
+		if elemId(call, 0) != msgCall || elemString(call, 3) != "com.add" {
+			t.Errorf("expected CALL, got %v", call)
+			return
+		}
+		writeMsg(conn, s, msgResult, elemId(call, 1), map[string]interface{}{}, []interface{}{3})
+		<-done
+	})
+	defer close(done)
+	cf := DefaultWamp
+	cf.Serializers = []Serializer{s}
+	client, err := cf.NewWampUrl(u, "realm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	got := make(chan ws.Event, 1)
+	client.Subscribe("com.test").BindAllFunc(func(e ws.Event) {
+		got <- e
+	})
+	select {
+	case e := <-got:
+		if e.GetChannel() != "com.test" || !reflect.DeepEqual(e.GetData(), []interface{}{"hi"}) {
+			t.Fatalf("bad event: %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	res, err := client.Call(ctx, "com.add", []interface{}{1, 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := toId(elem(res.Args, 0)); n != 3 {
+		t.Fatalf("expected 3, got %v", res.Args)
+	}
+}
+
+func TestSessionJSON(t *testing.T) {
+	testSession(t, JSONSerializer{})
+}
+
+func TestSessionMsgpack(t *testing.T) {
+	testSession(t, MsgpackSerializer{})
+}
@@ -0,0 +1,52 @@
+package websocket
+
+// Binder has the client level Bind methods and Use, they apply to the events
+// of all channels.  Clients embed it.
+type Binder struct {
+	channels  *Channels
+}
+
+func (b Binder) Bind(event string, h Handler) {
+	b.channels.Bind(event, h)
+}
+
+func (b Binder) Unbind(event string, h Handler) {
+	b.channels.Unbind(event, h)
+}
+
+func (b Binder) BindFunc(event string, h func(Event)) {
+	b.Bind(event, HandlerFunc(h))
+}
+
+// Does nothing, func handlers can't be unbound (see Channel).
+func (b Binder) UnbindFunc(event string, h func(Event)) {
+	b.Unbind(event, HandlerFunc(h))
+}
+
+func (b Binder) BindAll(h Handler) {
+	b.Bind("", h)
+}
+
+func (b Binder) UnbindAll(h Handler) {
+	b.Unbind("", h)
+}
+
+func (b Binder) BindAllFunc(h func(Event)) {
+	b.Bind("", HandlerFunc(h))
+}
+
+// Does nothing, func handlers can't be unbound (see Channel).
+func (b Binder) UnbindAllFunc(h func(Event)) {
+	b.Unbind("", HandlerFunc(h))
+}
+
+// Add middlewares for all events.
+func (b Binder) Use(mws ...Middleware) {
+	b.channels.Use(mws...)
+}
+
+func NewBinder(channels *Channels) Binder {
+	return Binder{
+		channels: channels,
+	}
+}
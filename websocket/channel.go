@@ -26,6 +26,9 @@ type Channel interface {
 	BindAll(h Handler)
 	UnbindAll(h Handler)
 
+	// Funcs can't be compared, so func handlers can't be unbound and the
+	// Unbind*Func methods do nothing.  Bind a comparable Handler (e.g. a
+	// pointer) to unbind it later.
 	BindFunc(event string, h func(Event))
 	UnbindFunc(event string, h func(Event))
 
@@ -34,3 +37,10 @@ type Channel interface {
 
 }
 
+// Optional interface for channels with their own middlewares.
+type MiddlewareChannel interface {
+	Channel
+
+	Use(mws ...Middleware)
+}
+
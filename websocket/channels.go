@@ -10,13 +10,43 @@ type Channels struct {
 	channels  map[string]Channel
 	global    Channel
 	connected bool
+	mws       []Middleware
+	chain     Handler
+	match     func(channel string, event Event) bool
+	onPanic   func(*HandlerError)
+}
+
+// Add middlewares for all events, they run before the channel middlewares.
+func (c *Channels) Use(mws ...Middleware) {
+	c.Lock()
+	defer c.Unlock()
+	c.mws = append(c.mws, mws...)
+	c.chain = Chain(HandlerFunc(c.dispatch), c.mws...)
 }
 
 func (c *Channels) HandleEvent(event Event) {
+	c.RLock()
+	chain := c.chain
+	c.RUnlock()
+	if chain != nil {
+		safeHandle(c.onPanic, chain, event)
+	} else {
+		c.dispatch(event)
+	}
+}
+
+func (c *Channels) dispatch(event Event) {
 	// send event to global channel
 	if c.global != nil {
 		c.global.HandleEvent(event)
 	}
+	if c.match != nil {
+		// send event to all channels matching it.
+		for _, ch := range c.matching(event) {
+			ch.HandleEvent(event)
+		}
+		return
+	}
 	channelName := event.GetChannel()
 	if channelName == "" {
 		// global only event.
@@ -29,23 +59,38 @@ func (c *Channels) HandleEvent(event Event) {
 	}
 }
 
-func (c *Channels) ConnectedState(connected bool) {
+func (c *Channels) matching(event Event) []Channel {
 	c.RLock()
 	defer c.RUnlock()
+	var list []Channel
+	for name, ch := range c.channels {
+		if c.match(name, event) {
+			list = append(list, ch)
+		}
+	}
+	return list
+}
+
+func (c *Channels) ConnectedState(connected bool) {
+	c.Lock()
 	// cache connected state
 	c.connected = connected
-	// notify all channels of the connection state
+	list := make([]Channel, 0, len(c.channels))
 	for _, ch := range c.channels {
+		list = append(list, ch)
+	}
+	c.Unlock()
+	// notify all channels of the connection state, without holding the lock
+	// the protocol might need to look up the channel.
+	for _, ch := range list {
 		ch.UpdateClientState(connected)
 	}
 }
 
 func (c *Channels) SubscriptionSucceded(channel string, succeded bool) {
-	c.RLock()
-	defer c.RUnlock()
 	ch := c.Find(channel)
 	if ch != nil {
-		ch.SetActive(true)
+		ch.SetActive(succeded)
 	}
 }
 
@@ -61,6 +106,9 @@ func (c *Channels) Find(channel string) Channel {
 }
 
 func (c *Channels) Add(channel string, ch Channel) {
+	if p, ok := ch.(panicReporter); ok {
+		p.setOnPanic(c.onPanic)
+	}
 	// empty channel name is for receiving events from all subscribed channels.
 	if channel == "" {
 		c.global = ch
@@ -68,9 +116,10 @@ func (c *Channels) Add(channel string, ch Channel) {
 	}
 	// mutex is for the 'channels' map
 	c.Lock()
-	defer c.Unlock()
 	c.channels[channel] = ch
-	if c.connected {
+	connected := c.connected
+	c.Unlock()
+	if connected {
 		ch.Subscribe()
 	}
 }
@@ -81,13 +130,19 @@ func (c *Channels) Remove(channel string) {
 		c.global = nil
 	}
 	// mutex is for the 'channels' map
-	c.Lock()
-	defer c.Unlock()
+	c.RLock()
 	ch := c.channels[channel]
-	if ch != nil && c.connected {
+	connected := c.connected
+	c.RUnlock()
+	// unsubscribe while the channel can still be found.
+	if ch != nil && connected {
 		ch.Unsubscribe()
 	}
-	delete(c.channels, channel)
+	c.Lock()
+	if c.channels[channel] == ch {
+		delete(c.channels, channel)
+	}
+	c.Unlock()
 }
 
 func (c *Channels) Bind(event string, h Handler) {
@@ -98,6 +153,14 @@ func (c *Channels) Unbind(event string, h Handler) {
 	c.global.Unbind(event, h)
 }
 
+// Report panics of the handlers and middlewares, nil to log them.  Set it
+// before adding channels.
+func (c *Channels) OnHandlerPanic(fn func(*HandlerError)) {
+	c.Lock()
+	defer c.Unlock()
+	c.onPanic = fn
+}
+
 func NewChannels(client ChannelClient) *Channels {
 	return &Channels{
 		client: client,
@@ -14,6 +14,18 @@ type Client interface {
 	Close()
 }
 
+// Optional interface for clients that handle binary messages.  Otherwise
+// binary messages are passed to HandleMessage.
+type BinaryClient interface {
+	HandleBinaryMessage([]byte) error
+}
+
+// Optional interface for clients that change the url for each connect
+// attempt (e.g. to add session resume or auth params).
+type UrlClient interface {
+	ConnectUrl(u string) string
+}
+
 type ChannelClient interface {
 	Client
 
@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Returns the key used to detect duplicate events, empty to never drop the
+// event.
+type KeyFunc func(Event) string
+
+// Key from a field of the event data (e.g. "id"), the data can be an object or
+// a JSON string of an object.
+func FieldKey(field string) KeyFunc {
+	return func(e Event) string {
+		data, ok := e.GetData().(map[string]interface{})
+		if !ok {
+			if err := json.Unmarshal([]byte(e.GetDataString()), &data); err != nil {
+				return ""
+			}
+		}
+		switch id := data[field].(type) {
+		case string:
+			return e.GetChannel() + "\x00" + id
+		case float64, json.Number:
+			buf, _ := json.Marshal(id)
+			return e.GetChannel() + "\x00" + string(buf)
+		}
+		return ""
+	}
+}
+
+// Key from a hash of the channel, event name and data.  Connection level events
+// (no channel) are not deduplicated.
+func HashKey(e Event) string {
+	if e.GetChannel() == "" {
+		return ""
+	}
+	h := sha1.New()
+	h.Write([]byte(e.GetChannel()))
+	h.Write([]byte{0})
+	h.Write([]byte(e.GetEvent()))
+	h.Write([]byte{0})
+	h.Write([]byte(e.GetDataString()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type dedupEntry struct {
+	key   string
+	seen  time.Time
+}
+
+// Dedup drops events that were already seen.  It remembers up to size keys
+// for the time window.
+type Dedup struct {
+	sync.Mutex
+	key      KeyFunc
+	size     int
+	window   time.Duration
+	lru      *list.List
+	keys     map[string]*list.Element
+}
+
+func (d *Dedup) expire(now time.Time) {
+	for {
+		el := d.lru.Back()
+		if el == nil {
+			return
+		}
+		entry := el.Value.(*dedupEntry)
+		if len(d.keys) <= d.size && (d.window <= 0 || now.Sub(entry.seen) < d.window) {
+			return
+		}
+		d.lru.Remove(el)
+		delete(d.keys, entry.key)
+	}
+}
+
+// Events of the protocol itself (e.g. subscription confirmations), they are
+// never dropped as duplicates.
+type InternalEvent interface {
+	Internal() bool
+}
+
+// Returns true if the event is a duplicate, otherwise remember it.
+func (d *Dedup) Duplicate(e Event) bool {
+	key := d.key(e)
+	if key == "" {
+		return false
+	}
+	d.Lock()
+	defer d.Unlock()
+	now := time.Now()
+	d.expire(now)
+	if _, ok := d.keys[key]; ok {
+		return true
+	}
+	d.keys[key] = d.lru.PushFront(&dedupEntry{key: key, seen: now})
+	d.expire(now)
+	return false
+}
+
+// Middleware that drops duplicate application events, install it with Use.
+func (d *Dedup) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(e Event) {
+			if ie, ok := e.(InternalEvent); ok && ie.Internal() {
+				next.HandleEvent(e)
+				return
+			}
+			if !d.Duplicate(e) {
+				next.HandleEvent(e)
+			}
+		})
+	}
+}
+
+func (d *Dedup) Reset() {
+	d.Lock()
+	defer d.Unlock()
+	d.lru.Init()
+	d.keys = make(map[string]*list.Element)
+}
+
+// New Dedup filter.  A window of zero only limits by size.
+func NewDedup(key KeyFunc, size int, window time.Duration) *Dedup {
+	if size <= 0 {
+		size = 1
+	}
+	return &Dedup{
+		key: key,
+		size: size,
+		window: window,
+		lru: list.New(),
+		keys: make(map[string]*list.Element),
+	}
+}
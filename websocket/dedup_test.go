@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	event     string
+	channel   string
+	data      string
+	internal  bool
+}
+
+func (e *testEvent) GetEvent() string { return e.event }
+func (e *testEvent) SetEvent(event string) { e.event = event }
+func (e *testEvent) GetChannel() string { return e.channel }
+func (e *testEvent) SetChannel(channel string) { e.channel = channel }
+func (e *testEvent) GetData() interface{} { return e.data }
+func (e *testEvent) SetData(data interface{}) {}
+func (e *testEvent) GetDataString() string { return e.data }
+func (e *testEvent) SetDataString(data string) { e.data = data }
+func (e *testEvent) Internal() bool { return e.internal }
+
+func TestDedupMiddleware(t *testing.T) {
+	channels := NewChannels(nil)
+	global := NewPublicChannel("", nil)
+	channels.Add("", global)
+	channels.Use(NewDedup(HashKey, 10, time.Minute).Middleware())
+	var got []string
+	global.BindAllFunc(func(e Event) {
+		got = append(got, e.GetEvent())
+	})
+	events := []Event{
+		&testEvent{event: "msg", channel: "a", data: `{"id":1}`},
+		&testEvent{event: "msg", channel: "a", data: `{"id":1}`},
+		// protocol events are never dropped.
+		&testEvent{event: "subscribed", channel: "a", data: `{}`, internal: true},
+		&testEvent{event: "subscribed", channel: "a", data: `{}`, internal: true},
+		&GapEvent{Channel: "a"},
+		&GapEvent{Channel: "a"},
+		&testEvent{event: "msg", channel: "a", data: `{"id":2}`},
+	}
+	for _, e := range events {
+		channels.HandleEvent(e)
+	}
+	expected := []string{"msg", "subscribed", "subscribed", GAP_EVENT, GAP_EVENT, "msg"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	}
+}
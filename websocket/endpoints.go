@@ -0,0 +1,174 @@
+package websocket
+
+import (
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// how long a failed endpoint is skipped (doubled for each failure in a row)
+	ENDPOINT_RETRY_WAIT = time.Second * 5
+	MAX_ENDPOINT_RETRY_WAIT = time.Minute * 5
+	// doubling more often would overflow (and is past the max wait anyway).
+	MAX_ENDPOINT_RETRY_SHIFT = 16
+)
+
+// EndpointResolver returns an ordered list of websocket urls.  The first url is
+// the preferred endpoint, the others are fallbacks.
+type EndpointResolver func() ([]string, error)
+
+type endpoint struct {
+	url         string
+	failures    uint
+	retryAt     time.Time
+}
+
+type endpoints struct {
+	sync.Mutex
+	resolver    EndpointResolver
+	list        []*endpoint
+	resolved    bool
+	current     *endpoint
+	connectedAt time.Time
+	stableTime  time.Duration
+}
+
+func (e *endpoints) setUrls(urls []string) {
+	// keep health information for endpoints that are still in the list.
+	old := make(map[string]*endpoint, len(e.list))
+	for _, ep := range e.list {
+		old[ep.url] = ep
+	}
+	list := make([]*endpoint, 0, len(urls))
+	for _, u := range urls {
+		ep := old[u]
+		if ep == nil {
+			ep = &endpoint{url: u}
+		}
+		list = append(list, ep)
+	}
+	e.list = list
+}
+
+func (e *endpoints) resolve() {
+	e.resolved = true
+	if e.resolver == nil {
+		return
+	}
+	urls, err := e.resolver()
+	if err != nil {
+		log.Println("Failed to resolve endpoints:", err)
+		return
+	}
+	if len(urls) == 0 {
+		return
+	}
+	e.setUrls(urls)
+}
+
+// Pick the first healthy endpoint in preference order.  Returns how long to wait
+// if all endpoints are waiting to be retried.
+func (e *endpoints) next() (string, time.Duration) {
+	e.Lock()
+	defer e.Unlock()
+	if !e.resolved {
+		// resolve on the socket's goroutine, the resolver might block.
+		e.resolve()
+	}
+	now := time.Now()
+	var best *endpoint
+	for _, ep := range e.list {
+		if !ep.retryAt.After(now) {
+			best = ep
+			break
+		}
+		// remember the endpoint that can be retried first.
+		if best == nil || ep.retryAt.Before(best.retryAt) {
+			best = ep
+		}
+	}
+	e.current = best
+	return best.url, best.retryAt.Sub(now)
+}
+
+func (e *endpoints) failed(ep *endpoint) {
+	ep.failures++
+	if len(e.list) < 2 {
+		// nothing to rotate to, the socket's reconnect delay is enough.
+		return
+	}
+	wait := MAX_ENDPOINT_RETRY_WAIT
+	if ep.failures <= MAX_ENDPOINT_RETRY_SHIFT {
+		wait = ENDPOINT_RETRY_WAIT << (ep.failures - 1)
+	}
+	if wait > MAX_ENDPOINT_RETRY_WAIT {
+		wait = MAX_ENDPOINT_RETRY_WAIT
+	}
+	ep.retryAt = time.Now().Add(wait)
+}
+
+// Current endpoint failed to connect or asked us to go away.
+func (e *endpoints) fail() {
+	e.Lock()
+	defer e.Unlock()
+	if e.current == nil {
+		return
+	}
+	e.failed(e.current)
+	e.connectedAt = time.Time{}
+	// refresh the endpoint list after each full rotation.
+	for _, ep := range e.list {
+		if ep.failures == 0 {
+			return
+		}
+	}
+	e.resolve()
+}
+
+func (e *endpoints) connected() {
+	e.Lock()
+	defer e.Unlock()
+	e.connectedAt = time.Now()
+}
+
+// Connection to the current endpoint was lost.  Short lived connections count as
+// a failure, so a flapping endpoint isn't retried right away.
+func (e *endpoints) disconnected() {
+	e.Lock()
+	defer e.Unlock()
+	ep := e.current
+	if ep == nil || e.connectedAt.IsZero() {
+		return
+	}
+	if time.Since(e.connectedAt) < e.stableTime {
+		e.failed(ep)
+	} else {
+		ep.failures = 0
+		ep.retryAt = time.Time{}
+	}
+	e.connectedAt = time.Time{}
+}
+
+func newEndpoints(primary string, cf Config) *endpoints {
+	e := &endpoints{
+		resolver: cf.EndpointResolver,
+		stableTime: cf.ActivityTimeout,
+	}
+	e.setUrls(append([]string{primary}, cf.FallbackUrls...))
+	return e
+}
+
+// Hide the password and query of an url (they can carry credentials) so
+// it can be logged.
+func redactUrl(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return "<invalid url>"
+	}
+	if p.RawQuery != "" {
+		p.RawQuery = "xxxxx"
+	}
+	return p.Redacted()
+}
@@ -21,10 +21,15 @@ func (e *Error) Error() string {
 	return e.reason
 }
 
-func (e *Error) Tiemout() bool {
+func (e *Error) Timeout() bool {
 	return e.timeout
 }
 
+// Deprecated: misspelled, use Timeout.
+func (e *Error) Tiemout() bool {
+	return e.Timeout()
+}
+
 func (e *Error) Temporary() bool {
 	return e.temp
 }
@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"log"
+	"time"
+)
+
+// Middleware wraps a Handler, e.g. to log, time or filter events.
+type Middleware func(Handler) Handler
+
+// Wrap h with the middlewares, the first one is the outermost.
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Sends an event to the server.
+type SendFunc func(Event)
+
+// Interceptor wraps outbound events, it can change or drop them.
+type Interceptor func(SendFunc) SendFunc
+
+// Wrap send with the interceptors, the first one is the outermost.
+func ChainSend(send SendFunc, ics ...Interceptor) SendFunc {
+	for i := len(ics) - 1; i >= 0; i-- {
+		send = ics[i](send)
+	}
+	return send
+}
+
+// Recover from panics in the wrapped handlers, so the outer middlewares still
+// run (e.g. Timing).  Handlers are always recovered by the channels, this is
+// only needed inside a chain.  Panics are reported to onPanic, or logged if it
+// is nil.
+func Recover(onPanic func(*HandlerError)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(e Event) {
+			safeHandle(onPanic, next, e)
+		})
+	}
+}
+
+// Log each event, logger can be nil for the standard logger.
+func Logger(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(e Event) {
+			logger.Println("Event:", e.GetChannel(), e.GetEvent(), e.GetDataString())
+			next.HandleEvent(e)
+		})
+	}
+}
+
+// Report how long the handlers took for each event.
+func Timing(report func(e Event, d time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(e Event) {
+			start := time.Now()
+			next.HandleEvent(e)
+			report(e, time.Since(start))
+		})
+	}
+}
+
+// Only pass events where keep returns true.
+func Filter(keep func(e Event) bool) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(e Event) {
+			if keep(e) {
+				next.HandleEvent(e)
+			}
+		})
+	}
+}
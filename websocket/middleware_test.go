@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Record when the wrapped handlers are entered and left.
+func trace(name string, got *[]string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(e Event) {
+			*got = append(*got, name + ">")
+			next.HandleEvent(e)
+			*got = append(*got, "<" + name)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var got []string
+	h := Chain(HandlerFunc(func(e Event) {
+		got = append(got, e.GetEvent())
+	}), trace("a", &got), trace("b", &got))
+	h.HandleEvent(&testEvent{event: "x"})
+	if strings.Join(got, " ") != "a> b> x <b <a" {
+		t.Fatalf("bad order: %v", got)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var reported []*HandlerError
+	var timed []string
+	h := Chain(HandlerFunc(func(e Event) {
+		panic("boom")
+	}), Timing(func(e Event, d time.Duration) {
+		timed = append(timed, e.GetEvent())
+	}), Recover(func(err *HandlerError) {
+		reported = append(reported, err)
+	}))
+	h.HandleEvent(&testEvent{event: "x", channel: "c"})
+	// the outer middleware still ran.
+	if len(timed) != 1 {
+		t.Fatalf("expected the event to be timed, got %v", timed)
+	}
+	if len(reported) != 1 || reported[0].Value != "boom" || reported[0].Channel != "c" || reported[0].Event != "x" {
+		t.Fatalf("bad panic reports: %v", reported)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	channels := NewChannels(nil)
+	global := NewPublicChannel("", nil)
+	channels.Add("", global)
+	channels.Use(Filter(func(e Event) bool {
+		return e.GetEvent() != "skip"
+	}))
+	var got []string
+	global.BindAllFunc(func(e Event) {
+		got = append(got, e.GetEvent())
+	})
+	for _, name := range []string{"a", "skip", "b"} {
+		channels.HandleEvent(&testEvent{event: name})
+	}
+	if strings.Join(got, ",") != "a,b" {
+		t.Fatalf("expected a,b, got %v", got)
+	}
+}
+
+// Protocol that sends events as "channel event data" lines.
+type lineProtocol struct{}
+
+func (p lineProtocol) HandshakeFrame() []byte { return nil }
+func (p lineProtocol) DecodeEvent(msg []byte) (Event, error) { return nil, nil }
+func (p lineProtocol) Classify(e Event) (MessageKind, error) { return KindEvent, nil }
+func (p lineProtocol) SubscribeFrame(channel string) []byte { return nil }
+func (p lineProtocol) UnsubscribeFrame(channel string) []byte { return nil }
+func (p lineProtocol) PingFrame() []byte { return nil }
+func (p lineProtocol) PongFrame() []byte { return nil }
+
+func (p lineProtocol) EncodeEvent(e Event) ([]byte, error) {
+	return []byte(e.GetChannel() + " " + e.GetEvent() + " " + e.GetDataString()), nil
+}
+
+func TestIntercept(t *testing.T) {
+	frames := make(chan string, 10)
+	serverUrl := wstest.Server(t, func(conn *websocket.Conn) {
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			frames <- string(msg)
+		}
+	})
+	u, _ := url.Parse(serverUrl)
+	client := DefaultConfig.NewProtocolClient(u, lineProtocol{})
+	defer client.Close()
+	// the first interceptor is the outermost, it sees the event first.
+	client.Intercept(func(next SendFunc) SendFunc {
+		return func(e Event) {
+			if e.GetEvent() != "secret" {
+				next(e)
+			}
+		}
+	}, func(next SendFunc) SendFunc {
+		return func(e Event) {
+			e.SetDataString("[" + e.GetDataString() + "]")
+			next(e)
+		}
+	})
+	client.SendEvent(&testEvent{channel: "c", event: "secret", data: "1"})
+	client.SendEvent(&testEvent{channel: "c", event: "msg", data: "2"})
+	select {
+	case f := <-frames:
+		if f != "c msg [2]" {
+			t.Fatalf("expected the intercepted event, got %q", f)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the event")
+	}
+}
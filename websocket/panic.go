@@ -0,0 +1,47 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// Panic recovered from an event handler or callback.
+type HandlerError struct {
+	Channel  string
+	Event    string
+	Value    interface{}
+	Stack    []byte
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("handler panic: channel=%q event=%q: %v", e.Channel, e.Event, e.Value)
+}
+
+// Call fn, recovering from panics.  They are reported to onPanic, or logged
+// if it is nil.
+func safeCall(onPanic func(*HandlerError), channel string, event string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &HandlerError{
+				Channel: channel,
+				Event: event,
+				Value: r,
+				Stack: debug.Stack(),
+			}
+			if onPanic != nil {
+				onPanic(err)
+			} else {
+				log.Println(err, "\n" + string(err.Stack))
+			}
+		}
+	}()
+	fn()
+}
+
+// Call the handler, recovering from panics.
+func safeHandle(onPanic func(*HandlerError), h Handler, e Event) {
+	safeCall(onPanic, e.GetChannel(), e.GetEvent(), func() {
+		h.HandleEvent(e)
+	})
+}
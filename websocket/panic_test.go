@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"testing"
+)
+
+type countHandler struct {
+	n  *int
+}
+
+func (h countHandler) HandleEvent(e Event) {
+	*h.n++
+}
+
+func TestHandlerPanic(t *testing.T) {
+	var reported []*HandlerError
+	channels := NewChannels(nil)
+	channels.OnHandlerPanic(func(err *HandlerError) {
+		reported = append(reported, err)
+	})
+	global := NewPublicChannel("", nil)
+	channels.Add("", global)
+	n := 0
+	global.BindFunc(GAP_EVENT, func(e Event) {
+		panic("boom")
+	})
+	global.Bind(GAP_EVENT, countHandler{&n})
+	channels.HandleEvent(&GapEvent{Channel: "test"})
+	if n != 1 {
+		t.Fatalf("expected the other handler to get the event, got %d", n)
+	}
+	if len(reported) != 1 || reported[0].Value != "boom" || reported[0].Channel != "test" || reported[0].Event != GAP_EVENT {
+		t.Fatalf("bad panic reports: %v", reported)
+	}
+}
+
+func TestUnbind(t *testing.T) {
+	ch := NewPublicChannel("test", nil)
+	n := 0
+	h := countHandler{&n}
+	f := func(e Event) {}
+	ch.Bind(GAP_EVENT, h)
+	ch.BindFunc(GAP_EVENT, f)
+	// HandlerFunc values can't be compared, they are left alone.
+	ch.UnbindFunc(GAP_EVENT, f)
+	ch.Unbind(GAP_EVENT, h)
+	if len(ch.handlers[GAP_EVENT]) != 1 {
+		t.Fatalf("expected only the func handler left, got %d", len(ch.handlers[GAP_EVENT]))
+	}
+	ch.HandleEvent(&GapEvent{Channel: "test"})
+	if n != 0 {
+		t.Fatal("unbound handler got the event")
+	}
+}
@@ -1,12 +1,17 @@
 package websocket
 
 import (
+	"github.com/gorilla/websocket"
+	"net/http"
 	"net/url"
 	"time"
 )
 
 type PlainClient struct {
 	sock         *Socket
+	pingMessage  string
+	pingFrames   bool
+	onMessage    func(typ int, msg []byte)
 }
 
 func (c *PlainClient) HandleDisconnect() bool {
@@ -14,9 +19,21 @@ func (c *PlainClient) HandleDisconnect() bool {
 }
 
 func (c *PlainClient) HandleConnected() {
+	// connect timeout is done, start heartbeats.
+	c.sock.SetActivityTimeout(0)
 }
 
 func (c *PlainClient) HandleMessage(msg []byte) error {
+	if c.onMessage != nil {
+		c.onMessage(websocket.TextMessage, msg)
+	}
+	return nil
+}
+
+func (c *PlainClient) HandleBinaryMessage(msg []byte) error {
+	if c.onMessage != nil {
+		c.onMessage(websocket.BinaryMessage, msg)
+	}
 	return nil
 }
 
@@ -25,7 +42,11 @@ func (c *PlainClient) SendMessage(msg []byte) {
 }
 
 func (c *PlainClient) SendPing() {
-	c.sock.SendMessage([]byte("PING"))
+	if c.pingFrames {
+		c.sock.SendPingFrame()
+		return
+	}
+	c.sock.SendMessage([]byte(c.pingMessage))
 }
 
 func (c *PlainClient) Close() {
@@ -36,6 +57,36 @@ type Config struct {
 	ConnectTimeout    time.Duration
 	ActivityTimeout   time.Duration
 	PingTimeout       time.Duration
+	// Send pings every ActivityTimeout even while receiving messages.  For
+	// protocols where the server expects heartbeats from the client.
+	ClientHeartbeat   bool
+	// Extra handshake headers and requested subprotocols.
+	Header            http.Header
+	Subprotocols      []string
+	// Fallback urls tried in order when the primary url fails.
+	FallbackUrls      []string
+	// Optional resolver for the endpoint list, replaces the primary & fallback urls.
+	EndpointResolver  EndpointResolver
+	// Capture raw frames, for debugging.
+	Recorder          Recorder
+	// Feed a recording to the client instead of connecting.
+	Replay            *Replay
+	// Called from the socket goroutine when the connection state changes.
+	OnStateChange     func(ConnState)
+	// Bound to all events of a ProtocolClient before it starts connecting, so
+	// it also gets the first connect events.
+	Handler           Handler
+	// Message PlainClient sends as a ping, "PING" if empty.
+	PingMessage       string
+	// PlainClient sends websocket ping frames instead of PingMessage.
+	PingFrames        bool
+	// Called from the socket goroutine with the messages PlainClient receives,
+	// typ is websocket.TextMessage or websocket.BinaryMessage.
+	OnMessage         func(typ int, msg []byte)
+	// Called when an event handler or callback panics, nil to log it.  The
+	// panic is recovered, the other handlers still get the event and the
+	// connection stays up.
+	OnHandlerPanic    func(*HandlerError)
 }
 
 var DefaultConfig = Config{
@@ -49,8 +100,16 @@ func (cf Config) NewClient(websocketUrl string) (Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	p := &PlainClient{}
-	p.sock = NewSocket(u, cf, p)
+	p := &PlainClient{
+		pingMessage: cf.PingMessage,
+		pingFrames: cf.PingFrames,
+		onMessage: cf.OnMessage,
+	}
+	if p.pingMessage == "" {
+		p.pingMessage = "PING"
+	}
+	p.sock = newSocket(u, cf, p)
+	go p.sock.run()
 	return p, nil
 }
 
@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"github.com/Neopallium/websocket-client-go/internal/wstest"
+	"github.com/gorilla/websocket"
+
+	"testing"
+	"time"
+)
+
+// Server that sends a text and a binary message, then reports the client's
+// ping.
+func pingServer(t *testing.T, pings chan<- string) string {
+	return wstest.Server(t, func(conn *websocket.Conn) {
+		conn.SetPingHandler(func(data string) error {
+			pings <- "ping frame"
+			return nil
+		})
+		conn.WriteMessage(websocket.TextMessage, []byte("hi"))
+		conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2})
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			pings <- string(msg)
+		}
+	})
+}
+
+func TestPlainClient(t *testing.T) {
+	for _, test := range []struct {
+		frames    bool
+		expected  string
+	}{
+		// zero value config sends "PING" messages.
+		{false, "PING"},
+		{true, "ping frame"},
+	} {
+		pings := make(chan string, 1)
+		u := pingServer(t, pings)
+		msgs := make(chan string, 2)
+		cf := Config{
+			ConnectTimeout: time.Second * 5,
+			ActivityTimeout: time.Second,
+			PingTimeout: time.Second * 5,
+			PingFrames: test.frames,
+			OnMessage: func(typ int, msg []byte) {
+				if typ == websocket.BinaryMessage {
+					msgs <- "binary " + string(rune('0' + len(msg)))
+				} else {
+					msgs <- string(msg)
+				}
+			},
+		}
+		client, err := cf.NewClient(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range []string{"hi", "binary 2", test.expected} {
+			ch := msgs
+			if expected == test.expected {
+				ch = pings
+			}
+			select {
+			case got := <-ch:
+				if got != expected {
+					t.Fatalf("expected %q, got %q", expected, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for", expected)
+			}
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"time"
+)
+
+// Kind of a decoded protocol message.
+type MessageKind int
+
+const (
+	// Event for the bound handlers.
+	KindEvent MessageKind = iota
+	// Handshake finished, channels can be subscribed.
+	KindConnected
+	// Server confirmed a channel subscription.
+	KindSubscribed
+	// Heartbeat from the server, answered with the PongFrame.
+	KindPing
+	// Reply to our PingFrame.
+	KindPong
+	// Server refused or ended a channel subscription.
+	KindUnsubscribed
+	// Consumed by the protocol (replies, acks), not sent to the handlers.
+	KindInternal
+)
+
+// Protocol is the codec for a channel based protocol.  It only deals with
+// framing, ProtocolClient drives the socket and channels with it.
+type Protocol interface {
+	// Frame to send once the websocket is connected, nil if the server starts
+	// the handshake.
+	HandshakeFrame() []byte
+
+	// Encode an event, a nil frame isn't sent (e.g. the protocol queued it).
+	EncodeEvent(e Event) ([]byte, error)
+	// Decode a message, a nil event is skipped.  Errors that aren't a
+	// DelayError only drop the message.
+	DecodeEvent(msg []byte) (Event, error)
+
+	// Classify a decoded event.  Return a DelayError to reconnect, or a
+	// non-temporary error to stop.
+	Classify(e Event) (MessageKind, error)
+
+	SubscribeFrame(channel string) []byte
+	UnsubscribeFrame(channel string) []byte
+
+	// Heartbeat frames, nil if the protocol doesn't have them.
+	PingFrame() []byte
+	PongFrame() []byte
+}
+
+// Optional interface for protocols where the server picks the activity
+// timeout during the handshake.
+type ActivityTimeoutProtocol interface {
+	ActivityTimeout(e Event) time.Duration
+}
+
+// Optional interface for protocols that send frames on their own (replies,
+// requests).  Called by NewProtocolClient before connecting.
+type ClientProtocol interface {
+	SetClient(c *ProtocolClient)
+}
+
+// Optional interface for protocols that track the connection, e.g. to fail
+// requests still waiting for a reply.
+type ConnectionProtocol interface {
+	// Websocket connected.  Return true if there is no handshake reply to wait
+	// for, the channels are subscribed right away.
+	Opened() bool
+	// Websocket disconnected.
+	Closed()
+}
+
+// Optional interface for protocols that pack more than one event in a message.
+type MultiEventProtocol interface {
+	DecodeEvents(msg []byte) ([]Event, error)
+}
+
+// Optional interface for protocols with binary messages.
+type BinaryProtocol interface {
+	DecodeBinary(msg []byte) ([]Event, error)
+	// Send frames as binary messages, checked for each frame.
+	BinaryFrames() bool
+}
+
+// Optional interface for protocols that send binary attachments after an
+// event's frame.
+type AttachmentProtocol interface {
+	EncodeAttachments(e Event) ([]byte, [][]byte, error)
+}
+
+// Optional interface for protocols with their own heartbeat logic, replaces
+// PingFrame.
+type PingProtocol interface {
+	SendPing()
+}
+
+// Optional interface for protocols that change the url before each connect.
+type UrlProtocol interface {
+	ConnectUrl(u string) string
+}
+
+// Optional interface for protocols with their own channel type.
+type ChannelProtocol interface {
+	NewChannel(channel string) Channel
+}
+
+// Optional interface for protocols where an event can match more than one
+// channel, e.g. topic filters with wildcards.
+type MatchProtocol interface {
+	MatchChannel(channel string, e Event) bool
+}
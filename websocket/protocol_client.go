@@ -0,0 +1,335 @@
+package websocket
+
+import (
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Generic ChannelClient for protocols that are just a codec.
+type ProtocolClient struct {
+	Binder
+	sock         *Socket
+	channels     *Channels
+	proto        Protocol
+	resume       *ResumeTracker
+	mu           sync.Mutex
+	ics          []Interceptor
+	send         SendFunc
+	onPanic      func(*HandlerError)
+}
+
+// Socket the client is driving, for protocols that change its timeouts.
+func (c *ProtocolClient) Socket() *Socket {
+	return c.sock
+}
+
+func (c *ProtocolClient) ConnectUrl(u string) string {
+	if p, ok := c.proto.(UrlProtocol); ok {
+		return p.ConnectUrl(u)
+	}
+	return u
+}
+
+func (c *ProtocolClient) HandleDisconnect() bool {
+	c.resume.Disconnected()
+	c.channels.ConnectedState(false)
+	if p, ok := c.proto.(ConnectionProtocol); ok {
+		p.Closed()
+	}
+	return true
+}
+
+func (c *ProtocolClient) HandleConnected() {
+	if p, ok := c.proto.(ConnectionProtocol); ok && p.Opened() {
+		c.handleConnected(nil)
+	}
+	if frame := c.proto.HandshakeFrame(); frame != nil {
+		c.SendMessage(frame)
+	}
+}
+
+func (c *ProtocolClient) handleConnected(event Event) {
+	var timeout time.Duration
+	if p, ok := c.proto.(ActivityTimeoutProtocol); ok && event != nil {
+		timeout = p.ActivityTimeout(event)
+	}
+	// connect timeout is done, start heartbeats.
+	c.sock.SetActivityTimeout(timeout)
+	// subscribe to channels.
+	c.channels.ConnectedState(true)
+}
+
+func (c *ProtocolClient) HandleMessage(msg []byte) error {
+	var events []Event
+	var err error
+	if p, ok := c.proto.(MultiEventProtocol); ok {
+		events, err = p.DecodeEvents(msg)
+	} else {
+		var event Event
+		event, err = c.proto.DecodeEvent(msg)
+		if event != nil {
+			events = []Event{event}
+		}
+	}
+	return c.handleEvents(events, err)
+}
+
+func (c *ProtocolClient) HandleBinaryMessage(msg []byte) error {
+	p, ok := c.proto.(BinaryProtocol)
+	if !ok {
+		return c.HandleMessage(msg)
+	}
+	events, err := p.DecodeBinary(msg)
+	return c.handleEvents(events, err)
+}
+
+func (c *ProtocolClient) handleEvents(events []Event, err error) error {
+	for _, event := range events {
+		if err := c.handleEvent(event); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		if _, ok := err.(DelayError); ok {
+			return err
+		}
+		// skip bad messages.
+		log.Println("Error decoding message:", err)
+	}
+	return nil
+}
+
+func (c *ProtocolClient) handleEvent(event Event) error {
+	kind, err := c.proto.Classify(event)
+	switch kind {
+	case KindConnected:
+		c.handleConnected(event)
+	case KindSubscribed:
+		c.channels.SubscriptionSucceded(event.GetChannel(), true)
+	case KindUnsubscribed:
+		c.channels.SubscriptionSucceded(event.GetChannel(), false)
+	case KindPing:
+		if frame := c.proto.PongFrame(); frame != nil {
+			c.SendMessage(frame)
+		}
+	case KindPong:
+		c.sock.HandlePong()
+	}
+	if kind != KindInternal {
+		c.channels.HandleEvent(event)
+	}
+	switch kind {
+	case KindEvent:
+		if p, ok := c.proto.(ResumeProtocol); ok {
+			c.resume.Seen(event.GetChannel(), p.MessageId(event))
+		}
+	case KindSubscribed:
+		c.checkGap(event.GetChannel())
+	}
+	return err
+}
+
+// tell handlers about messages missed while disconnected.
+func (c *ProtocolClient) checkGap(channel string) {
+	if lastId, gap := c.resume.Subscribed(channel); gap {
+		c.channels.HandleEvent(&GapEvent{
+			Channel: channel,
+			LastId: lastId,
+		})
+	}
+}
+
+// Handshake finished outside of Classify, e.g. in a reply.
+func (c *ProtocolClient) Connected() {
+	c.handleConnected(nil)
+}
+
+// Server confirmed a subscription outside of Classify, e.g. in a reply.
+func (c *ProtocolClient) Subscribed(channel string) {
+	c.channels.SubscriptionSucceded(channel, true)
+	c.checkGap(channel)
+}
+
+// Server refused or ended a subscription outside of Classify.
+func (c *ProtocolClient) Unsubscribed(channel string) {
+	c.channels.SubscriptionSucceded(channel, false)
+}
+
+// Record the id of a channel message that didn't come from DecodeEvent (e.g.
+// replayed in a reply).
+func (c *ProtocolClient) Seen(channel string, id string) {
+	c.resume.Seen(channel, id)
+}
+
+// Server didn't resume the channel from the last id, handlers get a gap
+// event once the subscription is confirmed.
+func (c *ProtocolClient) ResumeFailed(channel string) {
+	c.resume.ResumeFailed(channel)
+}
+
+// Send an event to the bound handlers, for events that don't come from
+// DecodeEvent (e.g. replies).
+func (c *ProtocolClient) Dispatch(event Event) {
+	c.channels.HandleEvent(event)
+}
+
+// Send a frame, as a binary message if the protocol's frames are binary.
+func (c *ProtocolClient) SendMessage(msg []byte) {
+	if p, ok := c.proto.(BinaryProtocol); ok && p.BinaryFrames() {
+		c.sock.SendBinaryMessage(msg)
+	} else {
+		c.sock.SendMessage(msg)
+	}
+}
+
+func (c *ProtocolClient) SendBinaryMessage(msg []byte) {
+	c.sock.SendBinaryMessage(msg)
+}
+
+func (c *ProtocolClient) SendPing() {
+	if p, ok := c.proto.(PingProtocol); ok {
+		p.SendPing()
+	} else if frame := c.proto.PingFrame(); frame != nil {
+		c.SendMessage(frame)
+	} else {
+		c.sock.SendPingFrame()
+	}
+}
+
+// Add interceptors for outbound events.
+func (c *ProtocolClient) Intercept(ics ...Interceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ics = append(c.ics, ics...)
+	c.send = ChainSend(c.sendEvent, c.ics...)
+}
+
+func (c *ProtocolClient) SendEvent(e Event) {
+	c.mu.Lock()
+	send := c.send
+	c.mu.Unlock()
+	// interceptors are user code.
+	c.SafeCall(e.GetChannel(), e.GetEvent(), func() {
+		send(e)
+	})
+}
+
+// Call a protocol callback (e.g. a reply handler), recovering from panics like
+// the event handlers.
+func (c *ProtocolClient) SafeCall(channel string, event string, fn func()) {
+	safeCall(c.onPanic, channel, event, fn)
+}
+
+func (c *ProtocolClient) sendEvent(e Event) {
+	if p, ok := c.proto.(AttachmentProtocol); ok {
+		buf, attachments, err := p.EncodeAttachments(e)
+		if err != nil {
+			log.Println("Error sending event:", err)
+			return
+		}
+		c.SendMessage(buf)
+		for _, a := range attachments {
+			c.sock.SendBinaryMessage(a)
+		}
+		return
+	}
+	buf, err := c.proto.EncodeEvent(e)
+	if err != nil {
+		log.Println("Error sending event:", err)
+		return
+	}
+	if buf != nil {
+		c.SendMessage(buf)
+	}
+}
+
+func (c *ProtocolClient) SendSubscribe(channel string) {
+	frame := c.proto.SubscribeFrame(channel)
+	resumed := false
+	if p, ok := c.proto.(ResumeProtocol); ok {
+		if lastId := c.resume.LastId(channel); lastId != "" {
+			frame = p.ResumeFrame(channel, lastId)
+			resumed = true
+		}
+	}
+	c.resume.Subscribing(channel, resumed)
+	if frame != nil {
+		c.SendMessage(frame)
+	}
+}
+
+func (c *ProtocolClient) SendUnsubscribe(channel string) {
+	c.resume.Forget(channel)
+	if frame := c.proto.UnsubscribeFrame(channel); frame != nil {
+		c.SendMessage(frame)
+	}
+}
+
+// Id of the last message seen on the channel.
+func (c *ProtocolClient) LastMessageId(channel string) string {
+	return c.resume.LastId(channel)
+}
+
+func (c *ProtocolClient) Close() {
+	c.sock.Close()
+}
+
+func (c *ProtocolClient) FindChannel(channel string) Channel {
+	return c.channels.Find(channel)
+}
+
+// Add a channel of a custom type, it is subscribed if the client is connected.
+func (c *ProtocolClient) AddChannel(channel string, ch Channel) {
+	c.channels.Add(channel, ch)
+}
+
+func (c *ProtocolClient) Subscribe(channel string) Channel {
+	ch := c.channels.Find(channel)
+	if ch == nil {
+		// create a new channel.
+		if p, ok := c.proto.(ChannelProtocol); ok {
+			ch = p.NewChannel(channel)
+		} else {
+			ch = NewPublicChannel(channel, c)
+		}
+		c.channels.Add(channel, ch)
+	}
+	return ch
+}
+
+func (c *ProtocolClient) Unsubscribe(channel string) {
+	c.channels.Remove(channel)
+	c.resume.Forget(channel)
+}
+
+func (cf Config) NewProtocolClient(u *url.URL, proto Protocol) *ProtocolClient {
+	c := &ProtocolClient{
+		proto: proto,
+		resume: NewResumeTracker(),
+		onPanic: cf.OnHandlerPanic,
+	}
+	c.send = c.sendEvent
+	c.channels = NewChannels(c)
+	c.channels.OnHandlerPanic(cf.OnHandlerPanic)
+	if p, ok := proto.(MatchProtocol); ok {
+		c.channels.match = p.MatchChannel
+	}
+	c.Binder = NewBinder(c.channels)
+	c.channels.Add("", NewPublicChannel("", c))
+	if cf.Handler != nil {
+		c.BindAll(cf.Handler)
+	}
+	c.sock = newSocket(u, cf, c)
+	if p, ok := proto.(ClientProtocol); ok {
+		p.SetClient(c)
+	}
+	// start connecting once the protocol is ready.
+	go c.sock.run()
+	return c
+}
+
+func NewProtocolClient(u *url.URL, proto Protocol) *ProtocolClient {
+	return DefaultConfig.NewProtocolClient(u, proto)
+}
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"reflect"
 	"sync"
 )
 
@@ -10,54 +11,92 @@ type PublicChannel struct {
 	client     ChannelClient
 	handlers   map[string][]Handler
 	active     bool
+	mws        []Middleware
+	chain      Handler
+	onPanic    func(*HandlerError)
+}
+
+// Set by Channels.Add, for channels with their own handlers.
+type panicReporter interface {
+	setOnPanic(fn func(*HandlerError))
+}
+
+func (c *PublicChannel) setOnPanic(fn func(*HandlerError)) {
+	c.Lock()
+	defer c.Unlock()
+	c.onPanic = fn
+}
+
+// Add middlewares for the events of this channel.
+func (c *PublicChannel) Use(mws ...Middleware) {
+	c.Lock()
+	defer c.Unlock()
+	c.mws = append(c.mws, mws...)
+	c.chain = Chain(HandlerFunc(c.dispatch), c.mws...)
 }
 
 func (c *PublicChannel) HandleEvent(event Event) {
+	c.RLock()
+	chain := c.chain
+	onPanic := c.onPanic
+	c.RUnlock()
+	if chain != nil {
+		safeHandle(onPanic, chain, event)
+	} else {
+		c.dispatch(event)
+	}
+}
+
+func (c *PublicChannel) dispatch(event Event) {
 	c.RLock()
 	defer c.RUnlock()
 	// send event to callbacks bound to this event.
 	for _, h := range c.handlers[event.GetEvent()] {
-		h.HandleEvent(event)
+		safeHandle(c.onPanic, h, event)
 	}
 	// send to callbacks bound to all handlers.
 	for _, h := range c.handlers[""] {
-		h.HandleEvent(event)
+		safeHandle(c.onPanic, h, event)
 	}
 }
 
 func (c *PublicChannel) UpdateClientState(connected bool) {
-	c.RLock()
-	defer c.RUnlock()
-	if connected && ! c.active {
-		// Client connected.  Make sure we subscribe to the chanenl.
-		c.Subscribe()
-	} else {
+	c.Lock()
+	active := c.active
+	if !connected {
 		// Client disconnect, de-activate the channel.
 		c.active = false
 	}
+	c.Unlock()
+	if connected && ! active {
+		// Client connected.  Make sure we subscribe to the chanenl.
+		c.Subscribe()
+	}
 }
 
 func (c *PublicChannel) SetActive(active bool) {
-	c.RLock()
-	defer c.RUnlock()
+	c.Lock()
+	defer c.Unlock()
 	c.active = active
 }
 
-func remove(a []Handler, h Handler) {
-	n := len(a)
-	if n == 0 {
-		return
+// Handlers are compared with ==, HandlerFunc values aren't comparable so they
+// can't be removed.
+func remove(a []Handler, h Handler) []Handler {
+	if h == nil || !reflect.TypeOf(h).Comparable() {
+		return a
 	}
-	for i, f := range a {
-		if f == h {
-			// replace with last handler
-			n--
-			a[i] = a[n]
-			a[n] = nil
+	res := a[:0]
+	for _, f := range a {
+		if f != h {
+			res = append(res, f)
 		}
 	}
-	// trim array
-	a = a[:n]
+	// clear removed handlers
+	for i := len(res); i < len(a); i++ {
+		a[i] = nil
+	}
+	return res
 }
 
 func (c *PublicChannel) Bind(event string, h Handler) {
@@ -69,7 +108,7 @@ func (c *PublicChannel) Bind(event string, h Handler) {
 func (c *PublicChannel) Unbind(event string, h Handler) {
 	c.Lock()
 	defer c.Unlock()
-	remove(c.handlers[event], h)
+	c.handlers[event] = remove(c.handlers[event], h)
 }
 
 func (c *PublicChannel) BindAll(h Handler) {
@@ -84,6 +123,7 @@ func (c *PublicChannel) BindFunc(event string, h func(Event)) {
 	c.Bind(event, HandlerFunc(h))
 }
 
+// Does nothing, func handlers can't be unbound (see Channel).
 func (c *PublicChannel) UnbindFunc(event string, h func(Event)) {
 	c.Unbind(event, HandlerFunc(h))
 }
@@ -92,6 +132,7 @@ func (c *PublicChannel) BindAllFunc(h func(Event)) {
 	c.Bind("", HandlerFunc(h))
 }
 
+// Does nothing, func handlers can't be unbound (see Channel).
 func (c *PublicChannel) UnbindAllFunc(h func(Event)) {
 	c.Unbind("", HandlerFunc(h))
 }
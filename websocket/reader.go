@@ -1,18 +1,29 @@
 package websocket
 
+import (
+	"github.com/gorilla/websocket"
+)
+
 // reader goroutine
 func (s *Socket) makeReader() {
 	ws := s.ws
-	in := make(chan []byte, IN_CHANNEL_SIZE)
+	in := make(chan message, IN_CHANNEL_SIZE)
+	// websocket pongs are passed to the state machine.
+	ws.SetPongHandler(func (string) error {
+		in <-message{typ: websocket.PongMessage}
+		return nil
+	})
 	go func () {
 		for {
-			_, buf, err := ws.ReadMessage()
+			mt, buf, err := ws.ReadMessage()
 			if err != nil {
 				// Close channel to signal that the WebSocket connection has closed.
 				close(in)
 				return
 			}
-			in <-buf
+			msg := message{typ: mt, data: buf}
+			s.record(true, msg)
+			in <-msg
 		}
 	} ()
 	s.in = in
@@ -0,0 +1,184 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Largest frame read from a binary recording.
+	MAX_FRAME_SIZE = 64 * 1024 * 1024
+)
+
+var (
+	ErrFrameTooLarge = errors.New("websocket: recorded frame too large")
+)
+
+// Websocket frame captured by a Recorder.
+type Frame struct {
+	Time     time.Time
+	Inbound  bool
+	Type     int
+	Data     []byte
+}
+
+// Recorder captures the raw frames of a Socket (Config.Recorder).
+type Recorder interface {
+	Record(f *Frame)
+}
+
+// Text frames are stored as text to keep recordings readable.
+type jsonFrame struct {
+	Time  time.Time `json:"time"`
+	Dir   string `json:"dir"`
+	Type  int `json:"type"`
+	Text  string `json:"text,omitempty"`
+	Data  []byte `json:"data,omitempty"`
+}
+
+// Writes frames as JSON lines.
+type JSONRecorder struct {
+	sync.Mutex
+	enc   *json.Encoder
+}
+
+func (r *JSONRecorder) Record(f *Frame) {
+	jf := jsonFrame{
+		Time: f.Time,
+		Dir: "out",
+		Type: f.Type,
+	}
+	if f.Inbound {
+		jf.Dir = "in"
+	}
+	if f.Type == websocket.TextMessage {
+		jf.Text = string(f.Data)
+	} else {
+		jf.Data = f.Data
+	}
+	r.Lock()
+	defer r.Unlock()
+	if err := r.enc.Encode(&jf); err != nil {
+		log.Println("Failed to record frame:", err)
+	}
+}
+
+func NewJSONRecorder(w io.Writer) *JSONRecorder {
+	return &JSONRecorder{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Writes frames in a compact binary format: unix time in nanoseconds (int64),
+// direction (1 for inbound), frame type, data length (uint32) and data.
+type BinaryRecorder struct {
+	sync.Mutex
+	w     io.Writer
+}
+
+func (r *BinaryRecorder) Record(f *Frame) {
+	hdr := make([]byte, 14)
+	binary.BigEndian.PutUint64(hdr[0:], uint64(f.Time.UnixNano()))
+	if f.Inbound {
+		hdr[8] = 1
+	}
+	hdr[9] = byte(f.Type)
+	binary.BigEndian.PutUint32(hdr[10:], uint32(len(f.Data)))
+	r.Lock()
+	defer r.Unlock()
+	if _, err := r.w.Write(append(hdr, f.Data...)); err != nil {
+		log.Println("Failed to record frame:", err)
+	}
+}
+
+func NewBinaryRecorder(w io.Writer) *BinaryRecorder {
+	return &BinaryRecorder{
+		w: w,
+	}
+}
+
+func ReadJSONFrames(r io.Reader) ([]*Frame, error) {
+	var frames []*Frame
+	dec := json.NewDecoder(r)
+	for {
+		var jf jsonFrame
+		if err := dec.Decode(&jf); err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return frames, err
+		}
+		f := &Frame{
+			Time: jf.Time,
+			Inbound: jf.Dir == "in",
+			Type: jf.Type,
+			Data: jf.Data,
+		}
+		if jf.Type == websocket.TextMessage {
+			f.Data = []byte(jf.Text)
+		}
+		frames = append(frames, f)
+	}
+}
+
+func ReadBinaryFrames(r io.Reader) ([]*Frame, error) {
+	var frames []*Frame
+	br := bufio.NewReader(r)
+	hdr := make([]byte, 14)
+	for {
+		if _, err := io.ReadFull(br, hdr); err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return frames, err
+		}
+		// don't trust the length of a corrupt recording.
+		size := binary.BigEndian.Uint32(hdr[10:])
+		if size > MAX_FRAME_SIZE {
+			return frames, ErrFrameTooLarge
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return frames, err
+		}
+		frames = append(frames, &Frame{
+			Time: time.Unix(0, int64(binary.BigEndian.Uint64(hdr[0:]))),
+			Inbound: hdr[8] == 1,
+			Type: int(hdr[9]),
+			Data: data,
+		})
+	}
+}
+
+// Load a recording, ".jsonl" and ".json" files are JSON lines, others binary.
+func LoadRecording(path string) ([]*Frame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if strings.HasSuffix(path, ".jsonl") || strings.HasSuffix(path, ".json") {
+		return ReadJSONFrames(file)
+	}
+	return ReadBinaryFrames(file)
+}
+
+func (s *Socket) record(inbound bool, msg message) {
+	if s.recorder == nil {
+		return
+	}
+	s.recorder.Record(&Frame{
+		Time: time.Now(),
+		Inbound: inbound,
+		Type: msg.typ,
+		Data: msg.data,
+	})
+}
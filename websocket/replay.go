@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"time"
+)
+
+// Replay feeds the inbound frames of a recording to the client instead of
+// connecting (Config.Replay).  Timeouts are disabled and outbound messages are
+// dropped, so the client sees the same frames every run.
+type Replay struct {
+	Frames  []*Frame
+	// Playback speed, 1 for the original timing, 2 for twice as fast.  Zero
+	// feeds the frames without waiting.
+	Speed   float64
+	done    chan struct{}
+	started bool
+}
+
+// Closed once all frames were handled.
+func (r *Replay) Done() <-chan struct{} {
+	return r.done
+}
+
+func NewReplay(frames []*Frame, speed float64) *Replay {
+	return &Replay{
+		Frames: frames,
+		Speed: speed,
+		done: make(chan struct{}),
+	}
+}
+
+// replay reader goroutine
+func (s *Socket) makeReplayReader() {
+	in := make(chan message, IN_CHANNEL_SIZE)
+	r := s.replay
+	go func () {
+		defer close(in)
+		var last time.Time
+		for _, f := range r.Frames {
+			if !f.Inbound {
+				continue
+			}
+			if r.Speed > 0 && !last.IsZero() {
+				wait := time.Duration(float64(f.Time.Sub(last)) / r.Speed)
+				select {
+				case <-time.After(wait):
+				case <-s.closeSocket:
+					return
+				}
+			}
+			last = f.Time
+			select {
+			case in <-message{typ: f.Type, data: f.Data}:
+			case <-s.closeSocket:
+				// the client stopped reading.
+				return
+			}
+		}
+	} ()
+	s.in = in
+}
+
+// drop outbound messages, they are still recorded.
+func (s *Socket) makeReplayWriter() {
+	out := s.out
+	stop := s.stopWriter
+	go func () {
+		for {
+			select {
+			case msg := <-out:
+				s.record(false, msg)
+			case <-stop:
+				return
+			}
+		}
+	} ()
+}
+
+func replayState(s *Socket) stateFn {
+	if s.replay.started {
+		// recording finished.
+		return nil
+	}
+	s.replay.started = true
+	s.makeReplayReader()
+	s.makeReplayWriter()
+	s.setState(StateConnected)
+	s.client.HandleConnected()
+	return connectedState
+}
@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestReplayReaderStopsOnClose(t *testing.T) {
+	frames := make([]*Frame, IN_CHANNEL_SIZE * 2)
+	for i := range frames {
+		frames[i] = &Frame{Inbound: true, Type: 1, Data: []byte("x")}
+	}
+	u, _ := url.Parse("ws://replay")
+	s := newSocket(u, Config{Replay: NewReplay(frames, 0)}, nil)
+	s.makeReplayReader()
+	// nobody reads, the reader blocks on the full channel until closed.
+	s.Close()
+	n := 0
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-s.in:
+			if !ok {
+				if n >= len(frames) {
+					t.Fatalf("reader kept sending after close: %d frames", n)
+				}
+				return
+			}
+			n++
+		case <-timeout:
+			t.Fatal("timeout waiting for the reader to stop")
+		}
+	}
+}
+
+func TestReadBinaryFrames(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewBinaryRecorder(&buf)
+	r.Record(&Frame{Time: time.Unix(1, 0), Inbound: true, Type: 1, Data: []byte("hi")})
+	frames, err := ReadBinaryFrames(bytes.NewReader(buf.Bytes()))
+	if err != nil || len(frames) != 1 || string(frames[0].Data) != "hi" || !frames[0].Inbound {
+		t.Fatalf("bad frames: %v %v", frames, err)
+	}
+	// truncated frame.
+	if _, err := ReadBinaryFrames(bytes.NewReader(buf.Bytes()[:buf.Len() - 1])); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	// corrupt length.
+	hdr := make([]byte, 14)
+	binary.BigEndian.PutUint32(hdr[10:], 0xffffffff)
+	if _, err := ReadBinaryFrames(bytes.NewReader(hdr)); err != ErrFrameTooLarge {
+		t.Fatalf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
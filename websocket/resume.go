@@ -0,0 +1,168 @@
+package websocket
+
+import (
+	"sync"
+)
+
+// Event sent to a channel's handlers when it was resubscribed after a
+// reconnect without replaying the missed messages.  It is sent once the server
+// confirms the subscription, so handlers can refetch the channel's state.
+const GAP_EVENT = "gap"
+
+// Optional interface for protocols that can replay missed messages when a
+// channel is resubscribed.
+type ResumeProtocol interface {
+	// Id or offset of a channel message, empty if it has none.
+	MessageId(e Event) string
+	// Subscribe frame that resumes after the last seen message.
+	ResumeFrame(channel string, lastId string) []byte
+}
+
+// Gap in a channel's messages.  Data is the id of the last message seen
+// before the gap (may be empty).
+type GapEvent struct {
+	Channel  string
+	LastId   string
+	event    string
+}
+
+func (e *GapEvent) GetEvent() string {
+	if e.event == "" {
+		return GAP_EVENT
+	}
+	return e.event
+}
+
+func (e *GapEvent) Internal() bool {
+	return true
+}
+
+func (e *GapEvent) SetEvent(event string) {
+	e.event = event
+}
+
+func (e *GapEvent) GetChannel() string {
+	return e.Channel
+}
+
+func (e *GapEvent) SetChannel(channel string) {
+	e.Channel = channel
+}
+
+func (e *GapEvent) GetData() interface{} {
+	return e.LastId
+}
+
+func (e *GapEvent) SetData(data interface{}) {
+	if id, ok := data.(string); ok {
+		e.LastId = id
+	}
+}
+
+func (e *GapEvent) GetDataString() string {
+	return e.LastId
+}
+
+func (e *GapEvent) SetDataString(data string) {
+	e.LastId = data
+}
+
+type resumeState struct {
+	lastId      string
+	subscribed  bool
+	reconnected bool
+	resuming    bool
+	gap         bool
+}
+
+// Tracks the last seen message of each channel, so a reconnect can resume or
+// report the gap.
+type ResumeTracker struct {
+	sync.Mutex
+	channels   map[string]*resumeState
+}
+
+func (r *ResumeTracker) state(channel string) *resumeState {
+	st := r.channels[channel]
+	if st == nil {
+		st = &resumeState{}
+		r.channels[channel] = st
+	}
+	return st
+}
+
+func (r *ResumeTracker) Seen(channel string, id string) {
+	if channel == "" || id == "" {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.state(channel).lastId = id
+}
+
+func (r *ResumeTracker) LastId(channel string) string {
+	r.Lock()
+	defer r.Unlock()
+	if st := r.channels[channel]; st != nil {
+		return st.lastId
+	}
+	return ""
+}
+
+// Channel is being (re)subscribed.  If it was subscribed before the last
+// disconnect and can't be resumed, there is a gap.
+func (r *ResumeTracker) Subscribing(channel string, resumed bool) {
+	r.Lock()
+	defer r.Unlock()
+	st := r.state(channel)
+	st.gap = st.reconnected && !resumed
+	st.resuming = resumed
+}
+
+// Server didn't replay the channel from the last id, call it before
+// Subscribed.
+func (r *ResumeTracker) ResumeFailed(channel string) {
+	r.Lock()
+	defer r.Unlock()
+	if st := r.channels[channel]; st != nil && st.resuming {
+		st.gap = true
+	}
+}
+
+// Subscription confirmed, returns true if handlers should get a gap event.
+func (r *ResumeTracker) Subscribed(channel string) (string, bool) {
+	r.Lock()
+	defer r.Unlock()
+	st := r.state(channel)
+	st.subscribed = true
+	st.reconnected = false
+	st.resuming = false
+	if !st.gap {
+		return "", false
+	}
+	st.gap = false
+	return st.lastId, true
+}
+
+func (r *ResumeTracker) Disconnected() {
+	r.Lock()
+	defer r.Unlock()
+	for _, st := range r.channels {
+		if st.subscribed {
+			st.reconnected = true
+			st.subscribed = false
+		}
+	}
+}
+
+func (r *ResumeTracker) Forget(channel string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.channels, channel)
+}
+
+func NewResumeTracker() *ResumeTracker {
+	return &ResumeTracker{
+		channels: make(map[string]*resumeState),
+	}
+}
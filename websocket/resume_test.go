@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"testing"
+)
+
+func TestResumeTracker(t *testing.T) {
+	r := NewResumeTracker()
+	// never confirmed, nothing was missed.
+	r.Subscribing("a", false)
+	r.Disconnected()
+	r.Subscribing("a", false)
+	if _, gap := r.Subscribed("a"); gap {
+		t.Fatal("unexpected gap before the first subscription")
+	}
+	r.Seen("a", "1")
+	r.Disconnected()
+	// resumed from the last id.
+	r.Subscribing("a", true)
+	if _, gap := r.Subscribed("a"); gap {
+		t.Fatal("unexpected gap after resume")
+	}
+	r.Seen("a", "2")
+	r.Disconnected()
+	// server couldn't resume.
+	r.Subscribing("a", true)
+	r.ResumeFailed("a")
+	if lastId, gap := r.Subscribed("a"); !gap || lastId != "2" {
+		t.Fatalf("expected gap after 2, got %q %v", lastId, gap)
+	}
+	r.Disconnected()
+	// no resume support.
+	r.Subscribing("a", false)
+	if lastId, gap := r.Subscribed("a"); !gap || lastId != "2" {
+		t.Fatalf("expected gap after 2, got %q %v", lastId, gap)
+	}
+	r.Forget("a")
+	if r.LastId("a") != "" {
+		t.Fatal("expected channel to be forgotten")
+	}
+}
+
+func TestGapEventName(t *testing.T) {
+	e := &GapEvent{Channel: "a"}
+	if e.GetEvent() != GAP_EVENT {
+		t.Fatalf("expected %s, got %s", GAP_EVENT, e.GetEvent())
+	}
+	e.SetEvent("refetch")
+	if e.GetEvent() != "refetch" {
+		t.Fatalf("expected refetch, got %s", e.GetEvent())
+	}
+}
@@ -2,8 +2,10 @@ package websocket
 
 import (
 	"github.com/gorilla/websocket"
+	"net/http"
 	"net/url"
 	"log"
+	"sync"
 	"time"
 )
 
@@ -14,6 +16,12 @@ const (
 
 type stateFn func(s *Socket) stateFn
 
+// Websocket message and it's frame type.
+type message struct {
+	typ     int
+	data    []byte
+}
+
 const (
 	MAX_RECONNECT_WAIT = time.Second * 30
 )
@@ -21,19 +29,33 @@ const (
 type Socket struct {
 	client             Client
 	url                string
+	endpoints          *endpoints
 	ws                 *websocket.Conn
-	in                 chan []byte
-	out                chan []byte
+	in                 chan message
+	outMu              sync.Mutex
+	out                chan message
+	stopWriter         chan struct{}
 	closeSocket        chan bool
+	closeOnce          sync.Once
 	lastActivity       time.Time
 	connectTimeout     time.Duration
 	activityTimeout    time.Duration
 	pingTimeout        time.Duration
 	connectDelay       time.Duration
+	clientHeartbeat    bool
+	header             http.Header
+	subprotocols       []string
+	recorder           Recorder
+	replay             *Replay
+	onStateChange      func(ConnState)
 	timeoutTimer       *TimeoutTimer
 }
 
 func (s *Socket) SetTimeout(reason TimeoutReason, d time.Duration) {
+	if s.replay != nil {
+		// replays don't depend on timing.
+		return
+	}
 	s.timeoutTimer.SetTimeout(reason, d)
 }
 
@@ -51,20 +73,24 @@ func (s *Socket) SetActivityTimeout(activityTimeout time.Duration) {
 }
 
 func (s *Socket) reset() {
-	if s.out != nil {
-		close(s.out)
-		// create a new out channel
-		s.out = make(chan []byte, OUT_CHANNEL_SIZE)
-	}
+	s.outMu.Lock()
+	// stop the writer, senders still holding the old channel drop their message.
+	close(s.stopWriter)
+	// create a new out channel
+	s.out = make(chan message, OUT_CHANNEL_SIZE)
+	s.stopWriter = make(chan struct{})
+	s.outMu.Unlock()
 	if s.ws != nil {
 		s.ws.Close()
 		s.ws = nil
 	}
 }
 
-// Close Websocket and don't reconnect.
+// Close Websocket and don't reconnect.  Safe to call more than once.
 func (s *Socket) Close() {
-	close(s.closeSocket)
+	s.closeOnce.Do(func () {
+		close(s.closeSocket)
+	})
 }
 
 func (s *Socket) sendPing() {
@@ -79,27 +105,62 @@ func (s *Socket) HandlePong() {
 	s.SetTimeout(ActivityTimeout, s.activityTimeout)
 }
 
+// Subprotocol selected by the server, valid once connected.
+func (s *Socket) Subprotocol() string {
+	if s.ws == nil {
+		return ""
+	}
+	return s.ws.Subprotocol()
+}
+
+func (s *Socket) SetPingTimeout(pingTimeout time.Duration) {
+	if pingTimeout > 0 {
+		s.pingTimeout = pingTimeout
+	}
+}
+
 func startState(s *Socket) stateFn {
+	if s.replay != nil {
+		return replayState
+	}
 	// handle delayed re-connects
-	if s.connectDelay > 0 {
-		if s.connectDelay > MAX_RECONNECT_WAIT {
-			s.connectDelay = MAX_RECONNECT_WAIT
+	if s.connectDelay > MAX_RECONNECT_WAIT {
+		s.connectDelay = MAX_RECONNECT_WAIT
+	}
+	// pick endpoint, it might still be waiting to be retried.
+	u, wait := s.endpoints.next()
+	delay := s.connectDelay
+	if wait > delay {
+		delay = wait
+	}
+	if delay > 0 {
+		if delay > MAX_RECONNECT_WAIT {
+			delay = MAX_RECONNECT_WAIT
 		}
-		time.Sleep(s.connectDelay)
+		time.Sleep(delay)
 	}
 	// Set connection timeout on dialer
-	dialer := websocket.DefaultDialer
+	dialer := *websocket.DefaultDialer
 	dialer.HandshakeTimeout = s.connectTimeout
+	dialer.Subprotocols = s.subprotocols
 	s.SetTimeout(ConnectTimeout, s.connectTimeout)
-	ws, _, err := dialer.Dial(s.url, nil)
+	s.setState(StateConnecting)
+	if c, ok := s.client.(UrlClient); ok {
+		u = c.ConnectUrl(u)
+	}
+	s.url = u
+	ws, _, err := dialer.Dial(u, s.header)
 	if err != nil {
-		log.Println("Error connecting:", err)
-		// increase delay & reconnect
+		log.Println("Error connecting:", redactUrl(u), err)
+		// mark endpoint as failed, increase delay & reconnect
+		s.endpoints.fail()
 		s.connectDelay += time.Second
 		return startState
 	}
 	// websocket connected
 	s.ws = ws
+	s.endpoints.connected()
+	s.setState(StateConnected)
 	// Start reader & writer
 	s.makeReader()
 	s.makeWriter()
@@ -108,15 +169,19 @@ func startState(s *Socket) stateFn {
 }
 
 func reconnectState(s *Socket) stateFn {
+	s.endpoints.disconnected()
 	s.reset()
+	s.setState(StateDisconnected)
 	if s.client.HandleDisconnect() {
 		return startState
 	}
+	s.setState(StateClosed)
 	return nil
 }
 
 func stopState(s *Socket) stateFn {
 	s.reset()
+	s.setState(StateClosed)
 	return nil
 }
 
@@ -132,6 +197,8 @@ func (s *Socket) errorState(err error) stateFn {
 			delay := err.Delay()
 			if delay > 0 {
 				s.connectDelay += delay
+				// server wants us to back off, try the next endpoint.
+				s.endpoints.fail()
 			} else {
 				s.connectDelay = 0
 			}
@@ -155,16 +222,31 @@ func timeoutState(s *Socket) stateFn {
 	return connectedState
 }
 
+func (s *Socket) handleMessage(msg message) error {
+	switch msg.typ {
+	case websocket.PongMessage:
+		s.HandlePong()
+		return nil
+	case websocket.BinaryMessage:
+		if c, ok := s.client.(BinaryClient); ok {
+			return c.HandleBinaryMessage(msg.data)
+		}
+	}
+	return s.client.HandleMessage(msg.data)
+}
+
 func connectedState(s *Socket) stateFn {
 	for {
 		// wait for event from reader or heartbeat
 		select {
-		case event := <-s.in:
-			if event == nil {
+		case msg, ok := <-s.in:
+			if !ok {
 				return reconnectState
 			}
-			s.updateActivity()
-			if err := s.client.HandleMessage(event); err != nil {
+			if !s.clientHeartbeat {
+				s.updateActivity()
+			}
+			if err := s.handleMessage(msg); err != nil {
 				return s.errorState(err)
 			}
 		case tick := <-s.timeoutTimer.C:
@@ -184,19 +266,34 @@ func (s *Socket) run() {
 	for state := startState; state != nil; {
 		state = state(s)
 	}
+	if s.replay != nil {
+		close(s.replay.done)
+	}
 }
 
-func NewSocket(u *url.URL, cf Config, client Client) *Socket {
-	s := &Socket{
+func newSocket(u *url.URL, cf Config, client Client) *Socket {
+	return &Socket{
 		client: client,
 		url: u.String(),
+		endpoints: newEndpoints(u.String(), cf),
 		connectTimeout: cf.ConnectTimeout,
 		activityTimeout: cf.ActivityTimeout,
 		pingTimeout: cf.PingTimeout,
-		out: make(chan []byte, OUT_CHANNEL_SIZE),
+		clientHeartbeat: cf.ClientHeartbeat,
+		header: cf.Header,
+		subprotocols: cf.Subprotocols,
+		recorder: cf.Recorder,
+		replay: cf.Replay,
+		onStateChange: cf.OnStateChange,
+		out: make(chan message, OUT_CHANNEL_SIZE),
+		stopWriter: make(chan struct{}),
 		closeSocket: make(chan bool),
 		timeoutTimer: newTimeoutTimer(NoTimeout, 0),
 	}
+}
+
+func NewSocket(u *url.URL, cf Config, client Client) *Socket {
+	s := newSocket(u, cf, client)
 	// start connect stat machine
 	go s.run()
 	return s
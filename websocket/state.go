@@ -0,0 +1,26 @@
+package websocket
+
+// Connection state of a Socket, reported to Config.OnStateChange.
+type ConnState int
+
+const (
+	StateConnecting ConnState = iota
+	StateConnected
+	StateDisconnected
+	StateClosed
+)
+
+var connStateNames = []string{"connecting", "connected", "disconnected", "closed"}
+
+func (st ConnState) String() string {
+	if st < 0 || int(st) >= len(connStateNames) {
+		return "unknown"
+	}
+	return connStateNames[st]
+}
+
+func (s *Socket) setState(state ConnState) {
+	if s.onStateChange != nil {
+		s.onStateChange(state)
+	}
+}
@@ -3,23 +3,61 @@ package websocket
 import (
 	"github.com/gorilla/websocket"
 	"log"
+	"time"
 )
 
+const (
+	PING_WRITE_WAIT = time.Second * 10
+)
+
+// Safe to call from any goroutine.  Messages sent while disconnected are
+// written once connected, a message racing a disconnect is dropped.
+func (s *Socket) send(msg message) {
+	s.outMu.Lock()
+	out := s.out
+	stop := s.stopWriter
+	s.outMu.Unlock()
+	select {
+	case out <- msg:
+	case <-stop:
+	case <-s.closeSocket:
+	}
+}
+
 func (s *Socket) SendMessage(msg []byte) {
-	s.out <- msg
+	s.send(message{typ: websocket.TextMessage, data: msg})
+}
+
+func (s *Socket) SendBinaryMessage(msg []byte) {
+	s.send(message{typ: websocket.BinaryMessage, data: msg})
+}
+
+// Send a websocket ping frame, for protocols without their own heartbeat.  The
+// pong is handled by the Socket.
+func (s *Socket) SendPingFrame() {
+	s.send(message{typ: websocket.PingMessage})
 }
 
 func (s *Socket) makeWriter() {
 	ws := s.ws
 	out := s.out
+	stop := s.stopWriter
 	go func () {
 		for {
-			msg, ok := <-out
-			if ! ok {
+			var msg message
+			select {
+			case msg = <-out:
+			case <-stop:
 				// stop writer
 				return
 			}
-			err := ws.WriteMessage(websocket.TextMessage, msg)
+			s.record(false, msg)
+			var err error
+			if msg.typ == websocket.PingMessage {
+				err = ws.WriteControl(msg.typ, msg.data, time.Now().Add(PING_WRITE_WAIT))
+			} else {
+				err = ws.WriteMessage(msg.typ, msg.data)
+			}
 			if err != nil {
 				log.Println("Writer error:", err)
 				return